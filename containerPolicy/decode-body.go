@@ -0,0 +1,223 @@
+package containerpolicy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+)
+
+// containerBody is the body of /containers/create or /containers/{id}/update.
+// Create keeps the resources at HostConfig, update has them at the top level
+type containerBody struct {
+	container.Config
+	HostConfig       *container.HostConfig
+	NetworkingConfig *network.NetworkingConfig
+	container.UpdateConfig
+	// HostConfig at the top of the create body, docker daemon still reads this deprecated place
+	topLevelHostConfig container.HostConfig
+}
+
+// foldKey turns the key into the same string for all keys equal without the case,
+// encoding/json matches the fields this way: "Devices", "devices" and "DEVICES" are one field
+func foldKey(key string) string {
+	folded := []rune(key)
+	for i, r := range folded {
+		// the smallest rune of the orbit, so K of Kelvin is k too
+		for next := unicode.SimpleFold(r); next != r; next = unicode.SimpleFold(next) {
+			if next < folded[i] {
+				folded[i] = next
+			}
+		}
+	}
+	return string(folded)
+}
+
+// checkDuplicateKeys walks through the JSON and fails on the key which is twice in the same object.
+// encoding/json takes the last of them, so "Privileged":true,"privileged":false is false for us and for docker daemon,
+// but nobody can be sure which one the reader of the log or the next version of docker sees
+func checkDuplicateKeys(decoder *json.Decoder) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		keys := make(map[string]bool)
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, ok := token.(string)
+			if !ok {
+				return fmt.Errorf("the key of the object isn't a string: %v", token)
+			}
+			if keys[foldKey(key)] {
+				return fmt.Errorf("the key %s is twice in the object", key)
+			}
+			keys[foldKey(key)] = true
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for decoder.More() {
+			if err := checkDuplicateKeys(decoder); err != nil {
+				return err
+			}
+		}
+	}
+	// the closing } or ]
+	_, err = decoder.Token()
+	return err
+}
+
+// decodeBody decodes the raw body like docker daemon does, but denies the duplicate keys.
+// The body must be the bytes docker daemon gets, not unescaped: "%22" inside of a string is only a string
+func decodeBody(body string, value interface{}) error {
+	if err := checkDuplicateKeys(json.NewDecoder(bytes.NewReader([]byte(body)))); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), value)
+}
+
+// decodeContainerBody decodes the body the same way docker daemon does,
+// so we judge what it will really get
+func decodeContainerBody(body string) (containerBody, error) {
+	var config containerBody
+	if err := decodeBody(body, &config); err != nil {
+		return config, err
+	}
+	// the fields can't be embedded twice, so the top level is decoded alone
+	err := json.Unmarshal([]byte(body), &config.topLevelHostConfig)
+	return config, err
+}
+
+// hostConfig returns HostConfig docker daemon takes: HostConfig of create or the top level of the body without it.
+// The same as getHostConfig of docker daemon, some resources of the top level fill the empty ones of HostConfig
+func (config containerBody) hostConfig() container.HostConfig {
+	if config.HostConfig == nil {
+		return config.topLevelHostConfig
+	}
+	hostConfig := *config.HostConfig
+	topLevel := config.topLevelHostConfig
+	if hostConfig.Memory == 0 {
+		hostConfig.Memory = topLevel.Memory
+	}
+	if hostConfig.MemorySwap == 0 {
+		hostConfig.MemorySwap = topLevel.MemorySwap
+	}
+	if hostConfig.CPUShares == 0 {
+		hostConfig.CPUShares = topLevel.CPUShares
+	}
+	if hostConfig.CpusetCpus == "" {
+		hostConfig.CpusetCpus = topLevel.CpusetCpus
+	}
+	if hostConfig.VolumeDriver == "" {
+		hostConfig.VolumeDriver = topLevel.VolumeDriver
+	}
+	return hostConfig
+}
+
+// resources returns the resources from both places of the body
+func (config containerBody) resources() []container.Resources {
+	return []container.Resources{config.UpdateConfig.Resources, config.hostConfig().Resources}
+}
+
+// fieldByKey looks for the field like encoding/json does: by the name of JSON without the case,
+// the fields of the embedded structs (Resources of HostConfig) are fields of the struct
+func fieldByKey(structure reflect.Value, nameOfKey string) (reflect.Value, bool) {
+	for i := 0; i < structure.NumField(); i++ {
+		fieldType := structure.Type().Field(i)
+		if fieldType.Anonymous && fieldType.Type.Kind() == reflect.Struct {
+			if field, found := fieldByKey(structure.Field(i), nameOfKey); found {
+				return field, true
+			}
+			continue
+		}
+		name := strings.Split(fieldType.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = fieldType.Name
+		}
+		if strings.EqualFold(name, nameOfKey) {
+			return structure.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// valuesOfField turns strings, bools, numbers and slices of them into the lowercased strings.
+// The empty string, null and [] have no values
+func valuesOfField(field reflect.Value) []string {
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if field.IsNil() {
+			return nil
+		}
+		return valuesOfField(field.Elem())
+	case reflect.String:
+		if field.String() == "" {
+			return nil
+		}
+		return []string{strings.ToLower(field.String())}
+	case reflect.Bool:
+		return []string{strconv.FormatBool(field.Bool())}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(field.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(field.Uint(), 10)}
+	case reflect.Slice:
+		var values []string
+		for i := 0; i < field.Len(); i++ {
+			values = append(values, valuesOfField(field.Index(i))...)
+		}
+		return values
+	}
+	return nil
+}
+
+// bindsOfMounts writes the bind mounts like Binds, --mount type=bind,src=/,dst=/host,readonly is "/:/host:ro"
+func bindsOfMounts(mounts []mount.Mount) []string {
+	var binds []string
+	for _, m := range mounts {
+		if m.Type != mount.TypeBind {
+			continue
+		}
+		bind := m.Source + ":" + m.Target
+		if m.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, strings.ToLower(bind))
+	}
+	return binds
+}
+
+// valuesOfKey returns the values of the field of Config or HostConfig with the name of the key,
+// so "PidMode": "host", "pidmode":"\u0068ost" and "PidMode":"HOST" are the same "host" for us and docker daemon.
+// Binds are the bind mounts of Mounts too, they mount the host the same way
+func valuesOfKey(config containerBody, nameOfKey string) []string {
+	var values []string
+	for _, structure := range []reflect.Value{reflect.ValueOf(config.Config), reflect.ValueOf(config.hostConfig())} {
+		if field, found := fieldByKey(structure, nameOfKey); found {
+			values = valuesOfField(field)
+			break
+		}
+	}
+	if strings.EqualFold(nameOfKey, "binds") {
+		values = append(values, bindsOfMounts(config.hostConfig().Mounts)...)
+	}
+	return values
+}
@@ -0,0 +1,47 @@
+package containerpolicy
+
+const (
+	profileType = "profile"
+)
+
+// Capabilities docker gives by default, the baseline profile allows to add only them
+const defaultCapabilities = "[audit_write,cap_audit_write,chown,cap_chown,dac_override,cap_dac_override," +
+	"fowner,cap_fowner,fsetid,cap_fsetid,kill,cap_kill,mknod,cap_mknod,net_bind_service,cap_net_bind_service," +
+	"net_raw,cap_net_raw,setfcap,cap_setfcap,setgid,cap_setgid,setpcap,cap_setpcap,setuid,cap_setuid," +
+	"sys_chroot,cap_sys_chroot]"
+
+const unconfinedSecurityOpt = "[apparmor=unconfined,apparmor:unconfined,seccomp=unconfined,seccomp:unconfined," +
+	"label=disable,label:disable,systempaths=unconfined]"
+
+// Built-in profiles, like Pod Security Standards of Kubernetes but for HostConfig and Config.
+// The policy selects one of them with Profile,<name>,profile,Use and
+// can override every rule with its own rule of the same key, type and kind
+var Profiles = map[string][]Rule{
+	"privileged": {},
+	"baseline": {
+		{Key: "Privileged", Value: "false", Type: "bool", Kind: ExpectToSee},
+		{Key: "CapAdd", Value: defaultCapabilities, Type: "slice", Kind: AllowToUse},
+		{Key: "NetworkMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "PidMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "IpcMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "UTSMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "UsernsMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "SecurityOpt", Value: unconfinedSecurityOpt, Type: "slice", Kind: DoesntExpectToSee},
+		{Key: "HostMounts", Value: "[]", Type: hostMountType, Kind: AllowToUse},
+	},
+	"restricted": {
+		{Key: "Privileged", Value: "false", Type: "bool", Kind: ExpectToSee},
+		{Key: "CapAdd", Value: "[net_bind_service,cap_net_bind_service]", Type: "slice", Kind: AllowToUse},
+		{Key: "CapDrop", Value: "[all]", Type: "slice", Kind: Required},
+		{Key: "NetworkMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "PidMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "IpcMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "UTSMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "UsernsMode", Value: "[host]", Type: "string", Kind: DoesntExpectToSee},
+		{Key: "SecurityOpt", Value: unconfinedSecurityOpt, Type: "slice", Kind: DoesntExpectToSee},
+		{Key: "SecurityOpt", Value: "[no-new-privileges|no-new-privileges:true|no-new-privileges=true]", Type: "slice", Kind: Required},
+		{Key: "ReadonlyRootfs", Value: "true", Type: "bool", Kind: Required},
+		{Key: "User", Value: "", Type: userType, Kind: NonRoot},
+		{Key: "HostMounts", Value: "[]", Type: hostMountType, Kind: AllowToUse},
+	},
+}
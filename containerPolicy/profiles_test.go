@@ -0,0 +1,210 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func restrictedBody() map[string]interface{} {
+	return map[string]interface{}{
+		"Image": "alpine",
+		"User":  "1000:1000",
+		"HostConfig": map[string]interface{}{
+			"Privileged":     false,
+			"CapAdd":         nil,
+			"CapDrop":        []string{"ALL"},
+			"NetworkMode":    "default",
+			"PidMode":        "",
+			"IpcMode":        "private",
+			"ReadonlyRootfs": true,
+			"SecurityOpt":    []string{"no-new-privileges"},
+			"Binds":          []string{"data:/data"},
+		},
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	PathToThePolicy = "testdata/profile_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	ciBot := identity.Identity{KeyHash: "9a1b2c3d", User: "ci-bot", UID: "1100", Team: "ci"}
+
+	writable := restrictedBody()
+	writable["HostConfig"].(map[string]interface{})["ReadonlyRootfs"] = false
+
+	root := restrictedBody()
+	root["User"] = "root"
+
+	noUser := restrictedBody()
+	delete(noUser, "User")
+
+	ptrace := restrictedBody()
+	ptrace["HostConfig"].(map[string]interface{})["CapAdd"] = []string{"SYS_PTRACE"}
+
+	sysAdmin := restrictedBody()
+	sysAdmin["HostConfig"].(map[string]interface{})["CapAdd"] = []string{"SYS_ADMIN"}
+
+	hostBind := restrictedBody()
+	hostBind["HostConfig"].(map[string]interface{})["Binds"] = []string{"/etc:/host-etc:ro"}
+
+	noCapDrop := restrictedBody()
+	delete(noCapDrop["HostConfig"].(map[string]interface{}), "CapDrop")
+
+	privileged := restrictedBody()
+	privileged["HostConfig"].(map[string]interface{})["Privileged"] = true
+
+	zeroUser := restrictedBody()
+	zeroUser["User"] = "00"
+
+	rootGroup := restrictedBody()
+	rootGroup["User"] = "0:0"
+
+	labeledPrivileged := restrictedBody()
+	labeledPrivileged["Labels"] = map[string]string{"role": "build"}
+	labeledPrivileged["HostConfig"].(map[string]interface{})["Privileged"] = true
+
+	topLevelPrivileged := restrictedBody()
+	delete(topLevelPrivileged, "HostConfig")
+	topLevelPrivileged["Privileged"] = true
+
+	testCases := []AdmitTestCase{
+		{
+			name:   "Restricted container",
+			body:   restrictedBody(),
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Writable rootfs",
+			body:   writable,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:readonlyrootfs"},
+		},
+		{
+			name:   "Root user",
+			body:   root,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:user"},
+		},
+		{
+			name:   "User of the image",
+			body:   noUser,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:user"},
+		},
+		{
+			name:   "Capability allowed by the policy",
+			body:   ptrace,
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Capability denied by the policy",
+			body:   sysAdmin,
+			caller: roman,
+			result: Result{answer: false, msg: "capadd"},
+		},
+		{
+			name:   "Host mount",
+			body:   hostBind,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:hostmounts"},
+		},
+		{
+			name:   "Capabilities are not dropped",
+			body:   noCapDrop,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:capdrop"},
+		},
+		{
+			name:   "Root with the leading zero",
+			body:   zeroUser,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:user"},
+		},
+		{
+			name:   "Root with the group",
+			body:   rootGroup,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:user"},
+		},
+		{
+			name:   "HostConfig at the top of the body",
+			body:   topLevelPrivileged,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:privileged"},
+		},
+		{
+			name:   "Profile of the label can't replace the profile of the caller",
+			body:   labeledPrivileged,
+			caller: roman,
+			result: Result{answer: false, msg: "restricted:privileged"},
+		},
+		{
+			name:   "Privileged profile for the team",
+			body:   privileged,
+			caller: ciBot,
+			result: Result{true, ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestProfilesOfRawBody(t *testing.T) {
+	PathToThePolicy = "testdata/profile_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	hostConfig := `"CapDrop":["ALL"],"ReadonlyRootfs":true,"SecurityOpt":["no-new-privileges"]`
+
+	testCases := []struct {
+		name   string
+		body   string
+		result Result
+	}{
+		{
+			name:   "Space after the colon",
+			body:   `{"Image":"alpine","User":"1000","HostConfig":{` + hostConfig + `,"PidMode": "host"}}`,
+			result: Result{answer: false, msg: "restricted:pidmode"},
+		},
+		{
+			name:   "Escaped value",
+			body:   `{"Image":"alpine","User":"1000","HostConfig":{` + hostConfig + `,"PidMode":"\u0068ost"}}`,
+			result: Result{answer: false, msg: "restricted:pidmode"},
+		},
+		{
+			name:   "Key in the other case",
+			body:   `{"Image":"alpine","User":"1000","HostConfig":{` + hostConfig + `,"pidmode":"HOST"}}`,
+			result: Result{answer: false, msg: "restricted:pidmode"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheContainerPolicy(testCase.body, roman)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestIsItAllowedHostPath(t *testing.T) {
+	allowed := []string{"/home/roman", "/cache/"}
+
+	assert.True(t, isItAllowedHostPath("/home/roman/projects", allowed))
+	assert.True(t, isItAllowedHostPath("/cache", allowed))
+	assert.False(t, isItAllowedHostPath("/home/roman/../../etc", allowed))
+	assert.False(t, isItAllowedHostPath("/home/romanov", allowed))
+}
@@ -0,0 +1,279 @@
+package containerpolicy
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/distribution/reference"
+)
+
+// Weight of the selectors. The rule with the heaviest selectors wins,
+// so user beats team and any of them beats the global rule.
+// Image and labels come from the body of the caller, they only break the tie
+// between the rules of the same user or team
+const (
+	imageSelector = 1 << iota
+	labelSelector
+	teamSelector
+	userSelector
+	// rules of the profile lose even to the global rules
+	belowGlobal = -1
+	// rules scoped only by image and labels don't compete, they are added to the winners
+	onlyTighten  = -2
+	profileGroup = "profile," + profileType + ",Use"
+)
+
+var (
+	// ImageNamesOfID tells the names of the image by its ID, the plugin asks docker daemon.
+	// The selector image is matched against the names, so the ID can't escape the rules
+	ImageNamesOfID func(id string) ([]string, error)
+)
+
+// Rule is a row of the policy: key,value,type,kind[,selectors].
+// Selectors are optional and look like user=ci-*;team=ci;image=docker:*-dind;label=role=ci,
+// all of them must match for the rule to be applied
+type Rule struct {
+	Key       string
+	Value     string
+	Type      string
+	Kind      string
+	Selectors map[string]string
+	// Profile is the name of the built-in profile the rule came from
+	Profile string
+}
+
+// Name is how the rule is shown at the deny message
+func (rule Rule) Name() string {
+	name := strings.ToLower(rule.Key)
+	if rule.Profile != "" {
+		return rule.Profile + ":" + name
+	}
+	return name
+}
+
+// Scope is everything selectors can match against
+type Scope struct {
+	Caller identity.Identity
+	Image  string
+	Labels map[string]string
+}
+
+// ScopeFromBody takes the image and labels of the container from the create body
+func ScopeFromBody(body string, caller identity.Identity) Scope {
+	var config struct {
+		Image  string
+		Labels map[string]string
+	}
+	// update body doesn't have them, the rules for image and labels won't be applied
+	_ = json.Unmarshal([]byte(body), &config)
+	return Scope{Caller: caller, Image: config.Image, Labels: config.Labels}
+}
+
+func parseSelectors(column string) map[string]string {
+	selectors := make(map[string]string)
+	for _, selector := range strings.Split(column, ";") {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		parts := strings.SplitN(selector, "=", 2)
+		if len(parts) != 2 {
+			log.Println("I don't know this selector:", selector)
+			continue
+		}
+		selectors[parts[0]] = parts[1]
+	}
+	return selectors
+}
+
+// imageNames returns the normalized names of the image for the selector image:
+// debian:12, library/debian:12 and docker.io/library/debian:12 are the same image
+func imageNames(image string) []string {
+	if image == "" {
+		return nil
+	}
+	if !imageIDRegexp.MatchString(image) {
+		return []string{NormalizeImage(image)}
+	}
+	if ImageNamesOfID == nil {
+		return []string{image}
+	}
+	names, err := ImageNamesOfID(image)
+	if err != nil {
+		log.Println("Can't find the names of the image:", err)
+		return []string{image}
+	}
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, NormalizeImage(name))
+	}
+	return normalized
+}
+
+// normalizeImagePattern puts the glob of the image into the form of NormalizeImage:
+// debian:* is docker.io/library/debian:*. The glob at the first component is left as it is
+func normalizeImagePattern(pattern string) string {
+	if named, err := reference.ParseNormalizedNamed(pattern); err == nil {
+		return reference.TagNameOnly(named).String()
+	}
+	first := strings.SplitN(pattern, "/", 2)[0]
+	if pattern == "" || strings.ContainsAny(pattern[:1], "*?[") || first != pattern && strings.ContainsAny(first, "*?[") {
+		return pattern
+	}
+
+	domain, remainder := "docker.io", pattern
+	if first != pattern && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		domain, remainder = first, strings.TrimPrefix(pattern, first+"/")
+	}
+	if domain == "index.docker.io" {
+		domain = "docker.io"
+	}
+	if domain == "docker.io" && !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+	return domain + "/" + remainder
+}
+
+func globMatch(pattern string, value string) bool {
+	match, err := path.Match(pattern, value)
+	if err != nil {
+		log.Println("Wrong pattern at the selector:", pattern)
+		return false
+	}
+	return match
+}
+
+// weight returns how specific the rule is and false if the rule doesn't match the scope,
+// images are the normalized names of the image of the scope
+func (rule Rule) weight(scope Scope, images []string) (int, bool) {
+	weight := 0
+	for selector, pattern := range rule.Selectors {
+		switch selector {
+		case "user":
+			if scope.Caller.User == "" || !globMatch(pattern, scope.Caller.User) {
+				return 0, false
+			}
+			weight |= userSelector
+		case "team":
+			if scope.Caller.Team == "" || !globMatch(pattern, scope.Caller.Team) {
+				return 0, false
+			}
+			weight |= teamSelector
+		case "label":
+			// label=key=value or label=key for any value
+			parts := strings.SplitN(pattern, "=", 2)
+			value, found := scope.Labels[parts[0]]
+			if !found || (len(parts) == 2 && !globMatch(parts[1], value)) {
+				return 0, false
+			}
+			weight |= labelSelector
+		case "image":
+			pattern = normalizeImagePattern(pattern)
+			matched := false
+			for _, image := range images {
+				matched = matched || globMatch(pattern, image)
+			}
+			if !matched {
+				return 0, false
+			}
+			weight |= imageSelector
+		default:
+			// the rule for someone we can't recognize
+			return 0, false
+		}
+	}
+	return weight, true
+}
+
+// LoadRules reads the policy and keeps only the rules for the scope.
+// If several rules with the same key, type and kind match,
+// only the most specific of them are applied.
+// The caller picks the image and labels, so the rules scoped only by them are always applied:
+// they can tighten the policy, but can't loosen it.
+// The selected profile is unfolded into its rules, any rule of the policy beats them
+func LoadRules(pathToThePolicy string, scope Scope) ([]Rule, error) {
+	file, err := os.Open(pathToThePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening the file: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// selectors are optional
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CSV: %v", err)
+	}
+
+	images := imageNames(scope.Image)
+	var matched []Rule
+	var weights []int
+	heaviest := make(map[string]int)
+	for _, row := range records {
+		if len(row) < 4 {
+			log.Println("Skip the short row of the policy:", row)
+			continue
+		}
+		rule := Rule{Key: row[0], Value: row[1], Type: row[2], Kind: row[3]}
+		if len(row) > 4 {
+			rule.Selectors = parseSelectors(row[4])
+		}
+
+		weight, ok := rule.weight(scope, images)
+		if !ok {
+			continue
+		}
+		if weight != 0 && weight < teamSelector {
+			weights = append(weights, onlyTighten)
+			matched = append(matched, rule)
+			continue
+		}
+		group := strings.ToLower(rule.Key) + "," + rule.Type + "," + rule.Kind
+		if current, found := heaviest[group]; !found || weight > current {
+			heaviest[group] = weight
+		}
+		weights = append(weights, weight)
+		matched = append(matched, rule)
+	}
+
+	// the heaviest profile and every profile scoped only by image and labels
+	weight, found := heaviest[profileGroup]
+	unfolded := !found
+	for i, rule := range matched {
+		if rule.Type != profileType || weights[i] != onlyTighten && (unfolded || weights[i] != weight) {
+			continue
+		}
+		if weights[i] != onlyTighten {
+			unfolded = true
+		}
+		profileRules, found := Profiles[rule.Value]
+		if !found {
+			return nil, fmt.Errorf("I don't know this profile: %s", rule.Value)
+		}
+		for _, profileRule := range profileRules {
+			profileRule.Profile = rule.Value
+			group := strings.ToLower(profileRule.Key) + "," + profileRule.Type + "," + profileRule.Kind
+			if _, found := heaviest[group]; !found {
+				heaviest[group] = belowGlobal
+			}
+			weights = append(weights, belowGlobal)
+			matched = append(matched, profileRule)
+		}
+	}
+
+	var rules []Rule
+	for i, rule := range matched {
+		group := strings.ToLower(rule.Key) + "," + rule.Type + "," + rule.Kind
+		if rule.Type != profileType && (weights[i] == onlyTighten || weights[i] == heaviest[group]) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,117 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopedRules(t *testing.T) {
+	PathToThePolicy = "testdata/scoped_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	ciBot := identity.Identity{KeyHash: "9a1b2c3d", User: "ci-bot", UID: "1100", Team: "ci"}
+	runner := identity.Identity{KeyHash: "5e6f7a8b", User: "runner-01", UID: "1101", Team: "ci"}
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Global rule denies docker.sock",
+			body: map[string]interface{}{
+				"Image": "docker:24-dind",
+				"Binds": []string{"/var/run/docker.sock:/var/run/docker.sock"},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "Team and image rule allows docker.sock",
+			body: map[string]interface{}{
+				"Image": "docker:24-dind",
+				"Binds": []string{"/var/run/docker.sock:/var/run/docker.sock"},
+			},
+			caller: ciBot,
+			result: Result{true, ""},
+		},
+		{
+			name: "Team rule doesn't apply to other images",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Binds": []string{"/var/run/docker.sock:/var/run/docker.sock"},
+			},
+			caller: ciBot,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "User rule beats team rule",
+			body: map[string]interface{}{
+				"Image": "docker:24-dind",
+				"Binds": []string{"/var/run/docker.sock:/var/run/docker.sock", "/cache:/cache"},
+			},
+			caller: runner,
+			result: Result{true, ""},
+		},
+		{
+			name: "Label rule can't loosen the global rule",
+			body: map[string]interface{}{
+				"Image":  "alpine",
+				"Labels": map[string]string{"role": "build"},
+				"Binds":  []string{"/cache:/cache"},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "Label rule tightens the user rule",
+			body: map[string]interface{}{
+				"Image":  "docker:24-dind",
+				"Labels": map[string]string{"role": "build"},
+				"Binds":  []string{"/var/run/docker.sock:/var/run/docker.sock"},
+			},
+			caller: runner,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "User rule and label rule both allow the bind",
+			body: map[string]interface{}{
+				"Image":  "alpine",
+				"Labels": map[string]string{"role": "build"},
+				"Binds":  []string{"/cache:/cache"},
+			},
+			caller: runner,
+			result: Result{true, ""},
+		},
+		{
+			name: "All selectors of the rule must match",
+			body: map[string]interface{}{
+				"Image":      "dind:latest",
+				"Privileged": true,
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "privileged"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestNormalizeImagePattern(t *testing.T) {
+	assert.Equal(t, "docker.io/library/debian:*", normalizeImagePattern("debian:*"))
+	assert.Equal(t, "docker.io/library/debian:*", normalizeImagePattern("docker.io/debian:*"))
+	assert.Equal(t, "docker.io/corp/*", normalizeImagePattern("corp/*"))
+	assert.Equal(t, "docker.io/library/alpine:latest", normalizeImagePattern("alpine"))
+	assert.Equal(t, "registry.corp.internal/prod/*", normalizeImagePattern("registry.corp.internal/prod/*"))
+	assert.Equal(t, "*/library/ubuntu", normalizeImagePattern("*/library/ubuntu"))
+}
@@ -0,0 +1,112 @@
+package containerpolicy
+
+import (
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+)
+
+const (
+	archiveType = "archive"
+	roleType    = "role"
+)
+
+var (
+	PathToTheArchivePolicy = "containerPolicy/archive_policy.csv"
+)
+
+// normalizeContainerPath turns the path inside of the container into the absolute path.
+// Docker resolves the relative path from the root of the container, "../" can't go upper than "/"
+func normalizeContainerPath(archivePath string) string {
+	return path.Clean("/" + strings.ToLower(archivePath))
+}
+
+// isItAncestor checks the path is the parent of one of prefixes: the archive of "/"
+// contains "/etc" and PUT into "/" can extract "etc/passwd"
+func isItAncestor(archivePath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if archivePath == "/" || strings.HasPrefix(prefix, archivePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheArchivePath checks the path of the archive:
+// 1) DoesntExpectToSee, if the path is inside of one of prefixes or is the parent of it - DENY
+// 2) AllowToUse, if the path is not inside of one of prefixes - DENY.
+// We don't see inside of the container, so a symlink is judged by its own path
+func complyTheArchivePath(archivePath string, kindOfPolicy string, valueFromCSV string) bool {
+	prefixes := sliceFromPolicy(valueFromCSV)
+
+	switch kindOfPolicy {
+	case DoesntExpectToSee:
+		return !isItAllowedHostPath(archivePath, prefixes) && !isItAncestor(archivePath, prefixes)
+	case AllowToUse:
+		return isItAllowedHostPath(archivePath, prefixes)
+	}
+	log.Println("I don't know this archive policy!")
+	return false
+}
+
+// complyTheRole checks the role of the caller from users.csv is one of valueFromPolitic
+func complyTheRole(role string, kindOfPolicy string, valueFromCSV string) bool {
+	if kindOfPolicy != AllowToUse {
+		log.Println("I don't know this role policy!")
+		return false
+	}
+	for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+		if allowToUse == strings.ToLower(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy for the path query of /containers/{id}/archive (docker cp). The rules live at archive_policy.csv:
+// 1) Write with type "archive" for PUT, Read for GET and HEAD - DoesntExpectToSee, AllowToUse
+// 2) Archive with type "role" - AllowToUse, only these roles can use docker cp
+func ComplyTheArchivePolicy(method string, archivePath string, caller identity.Identity, image string) (bool, string) {
+	rules, err := LoadRules(PathToTheArchivePolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	operation := "read"
+	if method == http.MethodPut {
+		operation = "write"
+	}
+	archivePath = normalizeContainerPath(archivePath)
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case archiveType:
+			if nameOfKey != operation {
+				continue
+			}
+			yes = complyTheArchivePath(archivePath, rule.Kind, valueFromCSV)
+		case roleType:
+			yes = complyTheRole(caller.Role, rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this archive policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
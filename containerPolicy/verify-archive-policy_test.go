@@ -0,0 +1,125 @@
+package containerpolicy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type ArchiveTestCase struct {
+	name   string
+	method string
+	path   string
+	caller identity.Identity
+	result Result
+}
+
+func TestComplyTheArchivePolicy(t *testing.T) {
+	PathToTheArchivePolicy = "testdata/archive_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra", Role: "developer"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "data", Role: "ops"}
+	guest := identity.Identity{KeyHash: "e51cc637", User: "guest", UID: "1002", Team: "infra"}
+
+	testCases := []ArchiveTestCase{
+		{
+			name:   "Copy into the app",
+			method: http.MethodPut,
+			path:   "/app/config",
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Copy into /etc",
+			method: http.MethodPut,
+			path:   "/etc/cron.d",
+			caller: roman,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Copy into /etc via ../",
+			method: http.MethodPut,
+			path:   "/app/../../etc/",
+			caller: roman,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Relative path to /usr/bin",
+			method: http.MethodPut,
+			path:   "usr//bin",
+			caller: roman,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Copy into the root",
+			method: http.MethodPut,
+			path:   "/",
+			caller: roman,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Copy into /usr",
+			method: http.MethodPut,
+			path:   "/usr",
+			caller: roman,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Read from /etc",
+			method: http.MethodGet,
+			path:   "/etc/hosts",
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Read the secrets",
+			method: http.MethodGet,
+			path:   "/run/secrets/db",
+			caller: roman,
+			result: Result{answer: false, msg: "read"},
+		},
+		{
+			name:   "Stat the secrets",
+			method: http.MethodHead,
+			path:   "/RUN/./secrets",
+			caller: roman,
+			result: Result{answer: false, msg: "read"},
+		},
+		{
+			name:   "Read the parent of the secrets",
+			method: http.MethodGet,
+			path:   "/run",
+			caller: roman,
+			result: Result{answer: false, msg: "read"},
+		},
+		{
+			name:   "Copy into the app for the team",
+			method: http.MethodPut,
+			path:   "/app",
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Copy outside of the app for the team",
+			method: http.MethodPut,
+			path:   "/opt",
+			caller: anna,
+			result: Result{answer: false, msg: "write"},
+		},
+		{
+			name:   "Caller without the role",
+			method: http.MethodGet,
+			path:   "/app/log",
+			caller: guest,
+			result: Result{answer: false, msg: "archive"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheArchivePolicy(testCase.method, testCase.path, testCase.caller, "alpine")
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+)
+
+const (
+	queryType     = "query"
+	remoteType    = "remote"
+	buildArgsType = "buildargs"
+)
+
+var (
+	PathToTheBuildPolicy = "containerPolicy/build_policy.csv"
+)
+
+// remoteSource turns the remote context into host/path, so
+// https://github.com/corp/api.git#main, git@github.com:corp/api.git and github.com/corp/api
+// are all github.com/corp/api
+func remoteSource(remote string) string {
+	remote = strings.ToLower(strings.TrimSpace(remote))
+	// #branch:subdirectory
+	remote = strings.SplitN(remote, "#", 2)[0]
+
+	if parsed, err := url.Parse(remote); err == nil && parsed.Host != "" {
+		remote = parsed.Hostname() + path.Clean("/"+parsed.Path)
+	} else {
+		// git@github.com:corp/api.git
+		if at := strings.Index(remote, "@"); at >= 0 {
+			remote = remote[at+1:]
+		}
+		remote = strings.Replace(remote, ":", "/", 1)
+		parts := strings.SplitN(remote, "/", 2)
+		if len(parts) == 2 {
+			remote = parts[0] + path.Clean("/"+parts[1])
+		}
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+}
+
+// complyTheQueryValues checks every value of the parameter of the query:
+// 1) AllowToUse, if the value doesn't match any of globs from politic - DENY
+// 2) DoesntExpectToSee, if the value matches one of globs from politic - DENY.
+// Empty value is the default of docker daemon, it is not checked
+func complyTheQueryValues(values []string, kindOfPolicy string, valueFromCSV string) bool {
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		isItMatched := false
+		for _, pattern := range sliceFromPolicy(valueFromCSV) {
+			if match, _ := path.Match(pattern, value); match {
+				isItMatched = true
+				break
+			}
+		}
+
+		switch kindOfPolicy {
+		case AllowToUse:
+			if !isItMatched {
+				return false
+			}
+		case DoesntExpectToSee:
+			if isItMatched {
+				return false
+			}
+		default:
+			log.Println("I don't know this query policy!")
+			return false
+		}
+	}
+	return true
+}
+
+// complyTheRemote checks the remote context comes from approved git hosts:
+// Remote,"[github.com/corp/*,gitlab.corp.internal/*]",remote,AllowToUse.
+// The context from the body is not remote, it is not checked
+func complyTheRemote(remote string, kindOfPolicy string, valueFromCSV string) bool {
+	if remote == "" {
+		return true
+	}
+	return complyTheImageSource(remoteSource(remote), kindOfPolicy, valueFromCSV)
+}
+
+// complyTheBuildArgs checks the build args from the JSON of buildargs:
+// 1) ForbiddenKeys, if the name matches one of globs from politic (*password*) - DENY
+// 2) DoesntExpectToSee, if the value matches one of globs from politic (ghp_*) - DENY
+func complyTheBuildArgs(buildArgs string, kindOfPolicy string, valueFromCSV string) bool {
+	if buildArgs == "" {
+		return true
+	}
+	var args map[string]*string
+	if err := json.Unmarshal([]byte(buildArgs), &args); err != nil {
+		return false
+	}
+
+	for name, value := range args {
+		switch kindOfPolicy {
+		case ForbiddenKeys:
+			if !complyTheQueryValues([]string{name}, DoesntExpectToSee, valueFromCSV) {
+				return false
+			}
+		case DoesntExpectToSee:
+			// --build-arg NAME takes the value from the environment of the client
+			if value != nil && !complyTheQueryValues([]string{*value}, DoesntExpectToSee, valueFromCSV) {
+				return false
+			}
+		default:
+			log.Println("I don't know this build args policy!")
+			return false
+		}
+	}
+	return true
+}
+
+// Policy for the query of POST /build. The rules live at build_policy.csv:
+// 1) networkmode, platform, cpusetcpus and other parameters with type "query" - AllowToUse, DoesntExpectToSee
+// 2) Remote with type "remote" - AllowToUse, DoesntExpectToSee for the git host and the repository
+// 3) BuildArgs with type "buildargs" - ForbiddenKeys for names, DoesntExpectToSee for values
+// 4) ExtraHosts with type "dns" - AllowToUse, DoesntExpectToSee, see matchHostOrAddress
+func ComplyTheBuildPolicy(query url.Values, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheBuildPolicy, Scope{Caller: caller})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case queryType:
+			yes = complyTheQueryValues(query[nameOfKey], rule.Kind, valueFromCSV)
+		case remoteType:
+			yes = complyTheRemote(query.Get("remote"), rule.Kind, valueFromCSV)
+		case buildArgsType:
+			yes = complyTheBuildArgs(strings.ToLower(query.Get("buildargs")), rule.Kind, valueFromCSV)
+		case dnsType:
+			yes = complyTheHostsOrAddresses(valuesOfExtraHosts(query["extrahosts"]), rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this build policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
+
+// LabelsFromBuild returns the labels of the image from the JSON of the labels query
+func LabelsFromBuild(query url.Values) map[string]string {
+	var labels map[string]string
+	_ = json.Unmarshal([]byte(query.Get("labels")), &labels)
+	return labels
+}
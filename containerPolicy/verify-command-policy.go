@@ -0,0 +1,187 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+)
+
+const (
+	commandType = "command"
+)
+
+var (
+	// sh -c "mount /dev/sda1 /mnt; chroot /mnt" runs two commands
+	shellSeparators = regexp.MustCompile("[;&|\\n]+|\\$\\(|`")
+	shells          = []string{"sh", "bash", "ash", "dash", "zsh", "ksh"}
+)
+
+// globToRegexp turns the pattern of the policy into the regexp, "*" matches anything
+func globToRegexp(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	return regexp.MustCompile("^" + strings.ReplaceAll(quoted, `\*`, ".*") + "$")
+}
+
+// withBaseName turns "/usr/bin/nsenter -t 1" into "nsenter -t 1"
+func withBaseName(commandLine string) string {
+	words := strings.SplitN(commandLine, " ", 2)
+	words[0] = path.Base(words[0])
+	return strings.Join(words, " ")
+}
+
+// commandLines returns what will be really run: the whole command line
+// and every command of the script for the shell form (sh -c "...")
+func commandLines(command []string) []string {
+	if len(command) == 0 {
+		return nil
+	}
+	whole := strings.ToLower(strings.TrimSpace(strings.Join(command, " ")))
+	lines := []string{whole, withBaseName(whole)}
+
+	for i := 0; i+2 < len(command); i++ {
+		program := path.Base(strings.ToLower(command[i]))
+		isItShell := false
+		for _, shell := range shells {
+			if program == shell {
+				isItShell = true
+			}
+		}
+		if !isItShell || command[i+1] != "-c" {
+			continue
+		}
+		for _, line := range shellSeparators.Split(strings.ToLower(command[i+2]), -1) {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "("))
+			if line != "" {
+				lines = append(lines, line, withBaseName(line))
+			}
+		}
+	}
+	return lines
+}
+
+// commandStarts returns the command line from every word of every command of it.
+// Any word can start the program: "env nsenter -t 1", "exec nsenter -t 1",
+// "timeout 5 nsenter -t 1" or sh -ec "nsenter -t 1", so DoesntExpectToSee checks them all
+func commandStarts(command []string) []string {
+	whole := strings.ToLower(strings.Join(command, " "))
+	// the quotes and the escapes of the shell don't change the word: n\senter and n''senter are nsenter
+	whole = strings.NewReplacer(`\`, "", `"`, "", "'", "", "(", " ", ")", " ", "{", " ", "}", " ").Replace(whole)
+
+	var starts []string
+	for _, line := range shellSeparators.Split(whole, -1) {
+		words := strings.Fields(line)
+		for i := range words {
+			starts = append(starts, withBaseName(strings.Join(words[i:], " ")))
+		}
+	}
+	return starts
+}
+
+// complyTheCommandLines checks Entrypoint + Cmd, or Cmd of exec:
+// 1) DoesntExpectToSee, if some of command lines or some of its starts matches one of patterns - DENY
+// 2) AllowToUse, if the whole command line doesn't match any of patterns - DENY
+func complyTheCommandLines(command []string, kindOfPolicy string, valueFromCSV string) bool {
+	lines := commandLines(command)
+	if len(lines) == 0 {
+		// the command of the image, we can't judge it here
+		return true
+	}
+	patterns := sliceFromPolicy(valueFromCSV)
+
+	switch kindOfPolicy {
+	case DoesntExpectToSee:
+		for _, line := range append(lines, commandStarts(command)...) {
+			for _, pattern := range patterns {
+				if globToRegexp(pattern).MatchString(line) {
+					return false
+				}
+			}
+		}
+		return true
+	case AllowToUse:
+		for _, pattern := range patterns {
+			// lines[0] and lines[1] are the whole command line with and without the path
+			if globToRegexp(pattern).MatchString(lines[0]) || globToRegexp(pattern).MatchString(lines[1]) {
+				return true
+			}
+		}
+		return false
+	}
+	log.Println("I don't know this command policy!")
+	return false
+}
+
+// complyTheInit checks the container runs under approved init:
+// Entrypoint,"[tini,dumb-init]",command,Required. --init of docker is approved too
+func complyTheInit(config containerBody, valueFromCSV string) bool {
+	if init := config.hostConfig().Init; init != nil && *init {
+		return true
+	}
+	if len(config.Entrypoint) == 0 {
+		return false
+	}
+	program := strings.ToLower(config.Entrypoint[0])
+	for _, approved := range sliceFromPolicy(valueFromCSV) {
+		if approved == program || approved == path.Base(program) {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheCommand checks Command rules against Entrypoint + Cmd
+// and Entrypoint rules against Entrypoint of the container
+func complyTheCommand(body string, nameOfKey string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+
+	switch nameOfKey {
+	case "command":
+		command := append([]string{}, config.Entrypoint...)
+		command = append(command, config.Cmd...)
+		return complyTheCommandLines(command, kindOfPolicy, valueFromCSV)
+	case "entrypoint":
+		if kindOfPolicy == Required {
+			return complyTheInit(config, valueFromCSV)
+		}
+		return complyTheCommandLines(config.Entrypoint, kindOfPolicy, valueFromCSV)
+	}
+	log.Println("I don't know this command:", nameOfKey)
+	return false
+}
+
+// ComplyTheExecCommand checks Cmd of POST /containers/{id}/exec against
+// the Command rules of the container policy. image is the image of the container,
+// so the rules scoped by image work for exec too
+func ComplyTheExecCommand(body string, caller identity.Identity, image string) (bool, string) {
+	rules, err := LoadRules(PathToThePolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	var config struct {
+		Cmd []string
+	}
+	if err := decodeBody(body, &config); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+
+	for _, rule := range rules {
+		if rule.Type != commandType || strings.ToLower(rule.Key) != "command" {
+			continue
+		}
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		if yes := complyTheCommandLines(config.Cmd, rule.Kind, strings.ToLower(valueFromCSV)); !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,200 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandRules(t *testing.T) {
+	PathToThePolicy = "testdata/command_policy.csv"
+	ImageNamesOfID = func(id string) ([]string, error) {
+		return []string{"debian:12", "corp/base:1.0"}, nil
+	}
+	defer func() { ImageNamesOfID = nil }()
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Good command",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"sleep", "infinity"},
+			},
+			result: Result{true, ""},
+		},
+		{
+			name: "Command of the image",
+			body: map[string]interface{}{
+				"Image": "alpine",
+			},
+			result: Result{true, ""},
+		},
+		{
+			name: "Nsenter at exec form",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"/usr/bin/nsenter", "-t", "1", "-m", "sh"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Chroot at shell form",
+			body: map[string]interface{}{
+				"Image":      "alpine",
+				"Entrypoint": []string{"/bin/sh", "-c"},
+				"Cmd":        []string{"echo hi && chroot /host /bin/bash"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Shell form as a string",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   "mount /dev/sda1 /mnt",
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Nsenter behind env",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"env", "HOME=/", "nsenter", "-t", "1", "-m", "sh"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Nsenter behind exec of the shell",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"sh", "-c", "exec nsenter -t 1 -m sh"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Shell with the flags before -c",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"sh", "-ec", "nsenter -t 1 -m sh"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Escaped and quoted nsenter",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"bash", "-c", `n\senter -t 1 || "nsenter" -t 1`},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Nsenter split by the empty single quotes",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"sh", "-c", "n''senter -t 1 -m sh"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Nsenter split by the empty double quotes",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Cmd":   []string{"sh", "-c", `n""senter -t 1 -m sh`},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Rule of the image",
+			body: map[string]interface{}{
+				"Image": "debian:12",
+				"Cmd":   []string{"apt-get", "install", "-y", "nmap"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Rule of the image with the domain",
+			body: map[string]interface{}{
+				"Image": "docker.io/library/debian:12",
+				"Cmd":   []string{"apt-get", "install", "-y", "nmap"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Rule of the image without the domain",
+			body: map[string]interface{}{
+				"Image": "library/debian:12",
+				"Cmd":   []string{"apt-get", "install", "-y", "nmap"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Rule of the image by the ID",
+			body: map[string]interface{}{
+				"Image": "sha256:3a4b5c6d7e8f",
+				"Cmd":   []string{"apt-get", "install", "-y", "nmap"},
+			},
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name: "Service without init",
+			body: map[string]interface{}{
+				"Image":  "alpine",
+				"Labels": map[string]string{"service": "billing"},
+				"Cmd":    []string{"/app/server"},
+			},
+			result: Result{answer: false, msg: "entrypoint"},
+		},
+		{
+			name: "Service with tini",
+			body: map[string]interface{}{
+				"Image":      "alpine",
+				"Labels":     map[string]string{"service": "billing"},
+				"Entrypoint": []string{"/sbin/tini", "--"},
+				"Cmd":        []string{"/app/server"},
+			},
+			result: Result{true, ""},
+		},
+		{
+			name: "Service with --init",
+			body: map[string]interface{}{
+				"Image":      "alpine",
+				"Labels":     map[string]string{"service": "billing"},
+				"Cmd":        []string{"/app/server"},
+				"HostConfig": map[string]interface{}{"Init": true},
+			},
+			result: Result{true, ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestComplyTheExecCommand(t *testing.T) {
+	PathToThePolicy = "testdata/command_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	yes, msg := ComplyTheExecCommand(`{"Cmd":["sh"],"AttachStdin":true,"Tty":true}`, roman, "alpine")
+	assert.Equal(t, Result{true, ""}, Result{yes, msg})
+
+	yes, msg = ComplyTheExecCommand(`{"Cmd":["sh","-c","nsenter -t 1 -a"]}`, roman, "alpine")
+	assert.Equal(t, Result{false, "command"}, Result{yes, msg})
+
+	yes, msg = ComplyTheExecCommand(`{"Cmd":["timeout","5","/usr/bin/nsenter","-t","1","-a"]}`, roman, "alpine")
+	assert.Equal(t, Result{false, "command"}, Result{yes, msg})
+
+	yes, msg = ComplyTheExecCommand(`{"Cmd":["apt-get","update"]}`, roman, "debian:12")
+	assert.Equal(t, Result{false, "command"}, Result{yes, msg})
+}
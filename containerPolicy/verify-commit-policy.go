@@ -0,0 +1,236 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// splitChangeArgs splits the arguments of the instruction by the spaces outside of the quotes:
+// LABEL a="b c" d=e is a=b c and d=e
+func splitChangeArgs(args string) []string {
+	var fields []string
+	var field strings.Builder
+	inQuotes := false
+	for _, r := range args {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+		default:
+			field.WriteRune(r)
+		}
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// listOfChangeArgs takes the JSON form ["/data","/logs"] or the form with the spaces /data /logs
+func listOfChangeArgs(args string) []string {
+	var list []string
+	if err := json.Unmarshal([]byte(args), &list); err == nil {
+		return list
+	}
+	return splitChangeArgs(args)
+}
+
+// instructionsOfChanges reads the changes like docker daemon does: it joins them with "\n"
+// and parses them as a Dockerfile, so one change can carry many instructions and "\" at the end
+// of the line continues the instruction at the next line. The comments and the parser directives
+// (# escape=`) can change how the lines are read, so we don't take them
+func instructionsOfChanges(changes []string) ([]string, error) {
+	var instructions []string
+	instruction := ""
+	for _, line := range strings.Split(strings.Join(changes, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			return nil, fmt.Errorf("the comments are not taken: %s", trimmed)
+		}
+		if trimmed == "" {
+			// the empty line doesn't break the continuation
+			continue
+		}
+		if strings.HasSuffix(trimmed, "\\") {
+			instruction += strings.TrimSuffix(strings.TrimRight(line, " \t"), "\\")
+			continue
+		}
+		instructions = append(instructions, strings.TrimSpace(instruction+line))
+		instruction = ""
+	}
+	if strings.TrimSpace(instruction) != "" {
+		instructions = append(instructions, strings.TrimSpace(instruction))
+	}
+	return instructions, nil
+}
+
+// configFromChanges applies the Dockerfile instructions of docker commit --change to the config.
+// Docker daemon takes only CMD, ENTRYPOINT, ENV, EXPOSE, LABEL, ONBUILD, USER, VOLUME, WORKDIR,
+// the other instructions are the error. Docker daemon expands $VARIABLE from ENV and drops the escapes
+// and the quotes of USER, we can't repeat it, so such USER, LABEL, EXPOSE and VOLUME are the error too
+func configFromChanges(config container.Config, changes []string) (container.Config, error) {
+	instructions, err := instructionsOfChanges(changes)
+	if err != nil {
+		return config, err
+	}
+	for _, change := range instructions {
+		parts := strings.Fields(change)
+		instruction := strings.ToUpper(parts[0])
+		args := strings.TrimSpace(strings.TrimPrefix(change, parts[0]))
+
+		switch instruction {
+		case "USER", "LABEL", "EXPOSE", "VOLUME":
+			if strings.ContainsAny(args, "$\\") || (instruction == "USER" && strings.ContainsAny(args, `"'`)) {
+				return config, fmt.Errorf("the variables, the escapes and the quotes are not taken: %s", change)
+			}
+		}
+
+		switch instruction {
+		case "USER":
+			config.User = args
+		case "LABEL":
+			if config.Labels == nil {
+				config.Labels = make(map[string]string)
+			}
+			fields := splitChangeArgs(args)
+			// LABEL key value is the old form
+			if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+				config.Labels[fields[0]] = strings.Join(fields[1:], " ")
+				continue
+			}
+			for _, field := range fields {
+				pair := strings.SplitN(field, "=", 2)
+				if len(pair) != 2 {
+					return config, fmt.Errorf("wrong LABEL: %s", change)
+				}
+				config.Labels[pair[0]] = pair[1]
+			}
+		case "EXPOSE":
+			if config.ExposedPorts == nil {
+				config.ExposedPorts = make(nat.PortSet)
+			}
+			for _, port := range splitChangeArgs(args) {
+				if !strings.Contains(port, "/") {
+					port += "/tcp"
+				}
+				config.ExposedPorts[nat.Port(port)] = struct{}{}
+			}
+		case "VOLUME":
+			if config.Volumes == nil {
+				config.Volumes = make(map[string]struct{})
+			}
+			for _, volume := range listOfChangeArgs(args) {
+				config.Volumes[volume] = struct{}{}
+			}
+		case "CMD", "ENTRYPOINT", "ENV", "ONBUILD", "WORKDIR":
+			// the container policy judges them at the creation of the container
+		default:
+			return config, fmt.Errorf("docker commit doesn't take %s", instruction)
+		}
+	}
+	return config, nil
+}
+
+// Policy for --change of docker commit, the rules of image_config_policy.csv judge what the changes put into the image:
+// 1) User with type "user" - USER of the changes or User of the body
+// 2) Labels.<key> with type "label" - MatchRegexp for the labels the changes set, LABEL authz.owner must be the caller's
+// 3) ExposedPorts with type "portrange" - EXPOSE of the changes
+// 4) Volumes with type "volume" - VOLUME of the changes.
+// The rest of the image is the container, it was judged at the creation, so RequiredKeys, age and architecture are skipped
+func ComplyTheCommitChanges(body string, changes []string, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheImageConfigPolicy, Scope{Caller: caller})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// docker CLI sends null without --change, the body is the config of the new image
+	var config container.Config
+	if strings.TrimSpace(body) != "" && strings.TrimSpace(body) != "null" {
+		if err := decodeBody(body, &config); err != nil {
+			return false, "Error decoding the body: " + err.Error()
+		}
+	}
+	config, err = configFromChanges(config, changes)
+	if err != nil {
+		return false, "changes: " + err.Error()
+	}
+	if !ComplyTheOwnerLabel(config.Labels, caller) {
+		return false, "labels." + OwnerLabel
+	}
+
+	for _, rule := range rules {
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+
+		yes := true
+		switch rule.Type {
+		case userType:
+			// without USER the image keeps the user of the container
+			if config.User != "" {
+				yes = complyTheUserName(config.User, rule.Kind, strings.ToLower(valueFromCSV))
+			}
+		case labelType:
+			labelKey := strings.TrimPrefix(rule.Key, "Labels.")
+			if _, found := config.Labels[labelKey]; found {
+				if yes, failedPolicy := complyTheImageLabels(config.Labels, rule.Key, rule.Kind, valueFromCSV); !yes {
+					return false, failedPolicy
+				}
+			}
+		case portRangeType:
+			yes = complyTheExposedPorts(containerBody{}, config, valueFromCSV)
+		case volumeType:
+			yes = complyTheVolumes(containerBody{}, config, rule.Kind, strings.ToLower(valueFromCSV))
+		case labelsType, ageType, architectureType:
+			// the rest of the image was judged at the creation of the container
+		default:
+			log.Println("I don't know this image config policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
+
+// ComplyTheCommitRepository checks the repository of docker commit is inside of the namespace of the caller:
+// roman/snapshot or registry.corp.internal/infra/api for roman of the team infra.
+// The commit without the repository makes the image without the name, it is allowed
+func ComplyTheCommitRepository(repository string, caller identity.Identity) bool {
+	if repository == "" {
+		return true
+	}
+	named, err := reference.ParseNormalizedNamed(repository)
+	if err != nil {
+		return false
+	}
+	namespace := strings.SplitN(reference.Path(named), "/", 2)[0]
+	for _, allowed := range []string{caller.User, caller.Team} {
+		if allowed != "" && strings.ToLower(allowed) == namespace && strings.Contains(reference.Path(named), "/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageIDFromCommit returns Id of the image docker daemon committed
+func ImageIDFromCommit(body string) string {
+	var response struct {
+		ID string `json:"Id"`
+	}
+	_ = json.Unmarshal([]byte(body), &response)
+	return response.ID
+}
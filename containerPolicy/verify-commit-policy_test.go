@@ -0,0 +1,119 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheCommitChanges(t *testing.T) {
+	PathToTheImageConfigPolicy = "testdata/image_config_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []struct {
+		name    string
+		body    string
+		changes []string
+		result  Result
+	}{
+		{
+			name:   "Commit without changes",
+			body:   "null",
+			result: Result{true, ""},
+		},
+		{
+			name:    "Commit with good changes",
+			changes: []string{"USER app", `LABEL org.opencontainers.image.source="https://github.com/corp/api" team=infra`, "EXPOSE 8080", "CMD [\"./api\"]"},
+			result:  Result{true, ""},
+		},
+		{
+			name:    "USER root",
+			changes: []string{"USER root"},
+			result:  Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "User of the body is root",
+			body:   `{"User":"0:0"}`,
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:    "Label of the source from other place",
+			changes: []string{"LABEL org.opencontainers.image.source https://gitlab.com/roman/api"},
+			result:  Result{answer: false, msg: "labels.org.opencontainers.image.source"},
+		},
+		{
+			name:    "Label of other's owner",
+			changes: []string{"LABEL authz.owner=anna"},
+			result:  Result{answer: false, msg: "labels.authz.owner"},
+		},
+		{
+			name:    "Privileged port",
+			changes: []string{"EXPOSE 80/tcp"},
+			result:  Result{answer: false, msg: "exposedports"},
+		},
+		{
+			name:    "Volume of /etc",
+			changes: []string{`VOLUME ["/data","/etc/ssl"]`},
+			result:  Result{answer: false, msg: "volumes"},
+		},
+		{
+			name:    "USER root after the newline",
+			changes: []string{"USER 1000\nUSER root"},
+			result:  Result{answer: false, msg: "user"},
+		},
+		{
+			name:    "USER root after the continuation",
+			changes: []string{"USER \\\nroot"},
+			result:  Result{answer: false, msg: "user"},
+		},
+		{
+			name:    "USER root with the leading zero",
+			changes: []string{"USER 00"},
+			result:  Result{answer: false, msg: "user"},
+		},
+		{
+			name:    "USER root behind the tab",
+			changes: []string{"USER\troot"},
+			result:  Result{answer: false, msg: "user"},
+		},
+		{
+			name:    "USER of the variable",
+			changes: []string{"ENV U=root", "USER $U"},
+			result:  Result{answer: false, msg: "changes: the variables, the escapes and the quotes are not taken: USER $U"},
+		},
+		{
+			name:    "USER of the quotes",
+			changes: []string{`USER "root"`},
+			result:  Result{answer: false, msg: `changes: the variables, the escapes and the quotes are not taken: USER "root"`},
+		},
+		{
+			name:    "Parser directive",
+			changes: []string{"# escape=`", "USER `", "root"},
+			result:  Result{answer: false, msg: "changes: the comments are not taken: # escape=`"},
+		},
+		{
+			name:    "RUN is not for commit",
+			changes: []string{"RUN chmod u+s /bin/sh"},
+			result:  Result{answer: false, msg: "changes: docker commit doesn't take RUN"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheCommitChanges(testCase.body, testCase.changes, roman)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestComplyTheCommitRepository(t *testing.T) {
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	assert.True(t, ComplyTheCommitRepository("", roman))
+	assert.True(t, ComplyTheCommitRepository("roman/snapshot", roman))
+	assert.True(t, ComplyTheCommitRepository("registry.corp.internal/infra/api", roman))
+	assert.False(t, ComplyTheCommitRepository("anna/snapshot", roman))
+	assert.False(t, ComplyTheCommitRepository("roman", roman))
+	assert.False(t, ComplyTheCommitRepository("snapshot", identity.Identity{KeyHash: "e51cc637"}))
+}
@@ -1,107 +1,173 @@
 package containerpolicy
 
 import (
-	"encoding/csv"
-	"fmt"
 	"log"
-	"os"
-	"regexp"
 	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
 )
 
 const (
 	ExpectToSee       = "ExpectToSee"
 	DoesntExpectToSee = "DoesntExpectToSee"
 	AllowToUse        = "AllowToUse"
-	PathToThePolicy   = "containerPolicy/container_policy.csv"
+	Required          = "Required"
+)
+
+var (
+	PathToThePolicy = "containerPolicy/container_policy.csv"
 )
 
-// Policy for creation container. There are 3 type of checking:
+// Policy for creation container. There are 4 type of checking:
 // 1) DoesntExpectToSee, if some of valueFromBody == valueFromPolitic - DENY
 // 2) AllowToUse, if some of valueFromBody != valueFromPolitic - DENY
 // 3) ExpectToSee, if valueFromBody != valueFromPolitic - DENY
-func ComplyTheContainerPolicy(body string) (bool, string) {
-	file, err := os.Open(PathToThePolicy)
+// 4) Required, if valueFromBody is absent or doesn't have every of valueFromPolitic - DENY.
+// Alternatives are separated by "|": "[no-new-privileges|no-new-privileges:true]"
+// valueFromPolitic can use the caller: /home/${user}:/work, ${team}.slice
+// Rules can be scoped by the caller, image and labels of the container, see LoadRules.
+// Rules of the built-in profile are named as <profile>:<key> at the answer
+func ComplyTheContainerPolicy(body string, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToThePolicy, ScopeFromBody(body, caller))
 	if err != nil {
-		e := fmt.Sprintf("Error opening the file: %e", err)
-		return false, e
+		return false, err.Error()
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+	// the rules read the typed body docker daemon gets, the regexps can be fooled by the spaces and the escapes
+	config, err := decodeContainerBody(body)
 	if err != nil {
-		e := fmt.Sprintf("Error reading CSV:%e", err)
-		return false, e
+		return false, "Error decoding the body: " + err.Error()
 	}
-	body = strings.ToLower(body)
-	for _, row := range records {
-		nameOfKey := strings.ToLower(row[0])
-		valueFromCSV := strings.ToLower(row[1])
-		typeOfData := row[2]
-		kindOfPolicy := row[3]
+	originalBody := body
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+		typeOfData := rule.Type
+		kindOfPolicy := rule.Kind
 
-		var searcher string
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			// fail closed, the rule was written for someone we don't know
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
 
 		switch typeOfData {
-		case "slice":
-			// will ignore null and []
-			searcher = fmt.Sprintf(`"%s":\s*\[([^\]]*)\]`, nameOfKey)
-		case "string":
-			// will ignore null
-			searcher = fmt.Sprintf(`"%s":"([^"]+)"`, nameOfKey)
-		case "bool":
-			searcher = fmt.Sprintf(`"%s":([^",]+)`, nameOfKey)
+		case portRangeType:
+			if yes := complyThePortRange(originalBody, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case userType:
+			if yes := complyTheUser(originalBody, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case hostMountType:
+			if yes := complyTheHostMounts(originalBody, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case sysctlType:
+			if yes := complyTheSysctls(originalBody, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case ulimitType:
+			if yes := complyTheUlimits(originalBody, nameOfKey, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case tmpfsType:
+			if yes := complyTheTmpfs(originalBody, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case sizeType, intType:
+			if yes := complyTheNumber(originalBody, nameOfKey, typeOfData, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case logDriverType:
+			if yes := complyTheLogDriver(originalBody, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case logOptionType:
+			// the regexp can be case sensitive
+			value, _ := caller.Expand(rule.Value)
+			if yes := complyTheLogOptions(originalBody, nameOfKey, kindOfPolicy, value); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case dnsType:
+			if yes := complyTheDNS(originalBody, nameOfKey, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case commandType:
+			if yes := complyTheCommand(originalBody, nameOfKey, kindOfPolicy, valueFromCSV); !yes {
+				return false, rule.Name()
+			}
+			continue
+		case deviceType:
+			// paths of devices are case sensitive
+			value, _ := caller.Expand(rule.Value)
+			if yes := complyTheDevices(originalBody, value); !yes {
+				return false, rule.Name()
+			}
+			continue
 		}
 
-		re := regexp.MustCompile(searcher)
-		// if someone will want to add the same key with a forbidden value for bybass
-		matches := re.FindAllStringSubmatch(body, -1)
-		for _, match := range matches {
-			if match != nil {
-				if kindOfPolicy == ExpectToSee {
-					if match[1] != valueFromCSV {
-						return false, nameOfKey
-					}
-				} else if kindOfPolicy == DoesntExpectToSee {
-					csv := strings.Trim(valueFromCSV, "[]")
-					sliceFromCSV := strings.Split(csv, ",")
-					// if will get: ["value1","value2","value3"]
-					// regexpr give us at match[1] - "value1","value2","value3"
-					// we should check every single value
-					valueOfMatches := strings.Split(match[1], ",")
+		if typeOfData != "slice" && typeOfData != "string" && typeOfData != "bool" {
+			// labels and others have their own checks
+			continue
+		}
 
-					for _, valueOfMatch := range valueOfMatches {
-						valueOfMatch := strings.Trim(valueOfMatch, "\"")
-						for _, dontExpect := range sliceFromCSV {
-							if dontExpect == valueOfMatch {
-								return false, nameOfKey
-							}
-						}
+		// null, "" and [] have no values
+		values := valuesOfKey(config, nameOfKey)
+		switch kindOfPolicy {
+		case ExpectToSee:
+			for _, value := range values {
+				if value != valueFromCSV {
+					return false, rule.Name()
+				}
+			}
+		case DoesntExpectToSee:
+			for _, value := range values {
+				for _, dontExpect := range sliceFromPolicy(valueFromCSV) {
+					if dontExpect == value {
+						return false, rule.Name()
 					}
-				} else if kindOfPolicy == AllowToUse {
-					csv := strings.Trim(valueFromCSV, "[]")
-					sliceFromCSV := strings.Split(csv, ",")
-					valueOfMatches := strings.Split(match[1], ",")
-
-					for _, valueOfMatch := range valueOfMatches {
-						valueOfMatch = strings.Trim(valueOfMatch, "\"")
-						isItValueOK := false
-						for _, allowToUse := range sliceFromCSV {
-							if allowToUse == valueOfMatch {
-								isItValueOK = true
-								continue
-							}
-						}
-						if !isItValueOK {
-							return false, nameOfKey
+				}
+			}
+		case AllowToUse:
+			for _, value := range values {
+				isItValueOK := false
+				for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+					if allowToUse == value {
+						isItValueOK = true
+					}
+				}
+				if !isItValueOK {
+					return false, rule.Name()
+				}
+			}
+		case Required:
+			for _, required := range sliceFromPolicy(valueFromCSV) {
+				isItValueOK := false
+				for _, value := range values {
+					for _, alternative := range strings.Split(required, "|") {
+						if alternative == value {
+							isItValueOK = true
 						}
 					}
-				} else {
-					log.Println("I don't know this policy!")
-					return true, ""
+				}
+				if !isItValueOK {
+					return false, rule.Name()
 				}
 			}
+		default:
+			log.Println("I don't know this policy!")
+			return true, ""
 		}
 	}
 	return true, ""
@@ -5,6 +5,7 @@ import (
 	fmt2 "fmt"
 	"testing"
 
+	"github.com/casbin/casbin-authz-plugin/identity"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,10 +17,13 @@ type Result struct {
 type AdmitTestCase struct {
 	name   string
 	body   map[string]interface{}
+	caller identity.Identity
 	result Result
 }
 
 func TestComplyTheContainerPolicy(t *testing.T) {
+	// go test runs at the directory of the package
+	PathToThePolicy = "container_policy.csv"
 
 	testCases := []AdmitTestCase{
 		{
@@ -30,7 +34,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"Privileged":       false,
 				"ReadonlyRootfs":   false,
@@ -45,7 +49,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"Privileged":       true,
 				"ReadonlyRootfs":   false,
@@ -61,12 +65,12 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"privileged":       true,
 				"ReadonlyRootfs":   false,
 			},
-			result: Result{answer: false, msg: "privileged"},
+			result: Result{answer: false, msg: "Error decoding the body: the key privileged is twice in the object"},
 		},
 		{
 			name: "NetworkMode:host container",
@@ -77,7 +81,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"NetworkMode":      "host",
 			},
@@ -92,7 +96,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"NetworkMode":      "default",
 			},
@@ -107,7 +111,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"PublishAllPorts":  false,
 				"NetworkMode":      "",
 			},
@@ -122,7 +126,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"Binds":            []string{"/home/user/someFile.txt:/app"},
 				"NetworkMode":      "",
 			},
@@ -133,11 +137,11 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 			body: map[string]interface{}{
 				"MemorySwappiness": 60,
 				"OomKillDisable":   false,
-				"SecurityOpt":      "",
+				"SecurityOpt":      []string{},
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"PidsLimit":        0,
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"Binds":            []string{"/var/run/docker.sock:/var/run/docker.sock"},
 				"NetworkMode":      "",
 			},
@@ -148,11 +152,11 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 			body: map[string]interface{}{
 				"MemorySwappiness": 60,
 				"OomKillDisable":   false,
-				"SecurityOpt":      "",
+				"SecurityOpt":      []string{},
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"IpcMode":          "",
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"Binds":            []string{"/var/run/docker.sock:/var/run/docker.sock", "/:/host/"},
 				"NetworkMode":      "",
 			},
@@ -163,27 +167,27 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 			body: map[string]interface{}{
 				"MemorySwappiness": 60,
 				"OomKillDisable":   false,
-				"SecurityOpt":      "",
+				"SecurityOpt":      []string{},
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"IpcMode":          "none",
 				"ipcMode":          "host",
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"Binds":            []string{},
 				"NetworkMode":      "",
 			},
-			result: Result{answer: false, msg: "ipcmode"},
+			result: Result{answer: false, msg: "Error decoding the body: the key ipcMode is twice in the object"},
 		},
 		{
 			name: "Good IpcMode container",
 			body: map[string]interface{}{
 				"MemorySwappiness": 60,
 				"OomKillDisable":   false,
-				"SecurityOpt":      "",
+				"SecurityOpt":      []string{},
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"IpcMode":          "none",
-				"PortBindings":     "{}",
+				"PortBindings":     map[string]interface{}{},
 				"NetworkMode":      "",
 			},
 			result: Result{answer: true, msg: ""},
@@ -193,14 +197,120 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 			body: map[string]interface{}{
 				"MemorySwappiness": 60,
 				"OomKillDisable":   false,
-				"SecurityOpt":      "",
+				"SecurityOpt":      []string{},
 				"OomScoreAdj":      500,
 				"PidMode":          "",
 				"IpcMode":          "none",
 				"Devices":          []string{"/app/overload"},
 				"NetworkMode":      "",
 			},
-			result: Result{answer: false, msg: "devices"},
+			result: Result{answer: false, msg: "Error decoding the body: json: cannot unmarshal string into containerBody.Devices.0 of type container.DeviceMapping"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestComplyTheContainerPolicyWithCaller(t *testing.T) {
+	PathToThePolicy = "testdata/template_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001"}
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Bind of own home directory",
+			body: map[string]interface{}{
+				"Privileged": false,
+				"Binds":      []string{"/home/roman:/work"},
+			},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Bind of other's home directory",
+			body: map[string]interface{}{
+				"Privileged": false,
+				"Binds":      []string{"/home/anna:/work"},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "Bind mount of own home directory",
+			body: map[string]interface{}{
+				"Privileged": false,
+				"HostConfig": map[string]interface{}{
+					"Mounts": []map[string]interface{}{{"Type": "bind", "Source": "/home/roman", "Target": "/work"}},
+				},
+			},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Bind mount of the root",
+			body: map[string]interface{}{
+				"Privileged": false,
+				"HostConfig": map[string]interface{}{
+					"Mounts": []map[string]interface{}{{"Type": "bind", "Source": "/", "Target": "/host"}},
+				},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "binds"},
+		},
+		{
+			name: "Own cgroup slice",
+			body: map[string]interface{}{
+				"CgroupParent": "infra.slice",
+			},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Caller without a team",
+			body: map[string]interface{}{
+				"Privileged": false,
+			},
+			caller: anna,
+			result: Result{answer: false, msg: "cgroupparent"},
+		},
+		{
+			name: "Ports inside of own range",
+			body: map[string]interface{}{
+				"CgroupParent": "infra.slice",
+				"HostConfig": map[string]interface{}{
+					"PortBindings": map[string]interface{}{
+						"80/tcp":  []map[string]string{{"HostIp": "", "HostPort": "10000"}},
+						"443/tcp": []map[string]string{{"HostIp": "", "HostPort": "10008-10009"}},
+						"22/tcp":  []map[string]string{{"HostIp": "", "HostPort": ""}},
+					},
+				},
+			},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Ports outside of own range",
+			body: map[string]interface{}{
+				"CgroupParent": "infra.slice",
+				"HostConfig": map[string]interface{}{
+					"PortBindings": map[string]interface{}{
+						"80/tcp": []map[string]string{{"HostIp": "", "HostPort": "10010"}},
+					},
+				},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "portbindings"},
 		},
 	}
 
@@ -212,7 +322,7 @@ func TestComplyTheContainerPolicy(t *testing.T) {
 				fmt2.Println("Error during Marshal into JSON:", err)
 				return
 			}
-			response, msg := ComplyTheContainerPolicy(string(jsonString))
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
 			assert.Equal(t, testCase.result, Result{response, msg})
 		})
 	}
@@ -0,0 +1,153 @@
+package containerpolicy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	deviceType    = "device"
+	requestPrefix = "request:"
+)
+
+// allowedDevice is an entry of the device allowlist: <host>[:<container>[:<permissions>]]
+// or request:<driver or capability> for DeviceRequests (--gpus)
+type allowedDevice struct {
+	pathOnHost      string
+	pathInContainer string
+	permissions     string
+}
+
+func parseDeviceAllowlist(valueFromCSV string) ([]allowedDevice, []string) {
+	var devices []allowedDevice
+	var requests []string
+	for _, entry := range sliceFromPolicy(valueFromCSV) {
+		if strings.HasPrefix(entry, requestPrefix) {
+			requests = append(requests, strings.TrimPrefix(entry, requestPrefix))
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		device := allowedDevice{pathOnHost: parts[0], pathInContainer: parts[0], permissions: "rwm"}
+		if len(parts) > 1 && parts[1] != "" {
+			device.pathInContainer = parts[1]
+		}
+		if len(parts) > 2 {
+			device.permissions = parts[2]
+		}
+		devices = append(devices, device)
+	}
+	return devices, requests
+}
+
+// isItSubset checks every permission is one of allowed: "r" is a subset of "rw"
+func isItSubset(permissions string, allowed string) bool {
+	for _, permission := range permissions {
+		if !strings.ContainsRune(allowed, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+func complyTheDeviceMapping(device container.DeviceMapping, allowlist []allowedDevice) bool {
+	// the same defaults docker uses
+	pathInContainer := device.PathInContainer
+	if pathInContainer == "" {
+		pathInContainer = device.PathOnHost
+	}
+	permissions := device.CgroupPermissions
+	if permissions == "" {
+		permissions = "rwm"
+	}
+
+	for _, allowed := range allowlist {
+		if path.Clean(device.PathOnHost) != allowed.pathOnHost {
+			continue
+		}
+		if match, _ := path.Match(allowed.pathInContainer, path.Clean(pathInContainer)); !match {
+			continue
+		}
+		if isItSubset(permissions, allowed.permissions) {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheCgroupRule checks the rule like "c 10:229 rwm" gives access only to allowed devices.
+// We stat the allowed device on the host to know its type, major and minor
+func complyTheCgroupRule(cgroupRule string, allowlist []allowedDevice) bool {
+	var deviceType, numbers, permissions string
+	if _, err := fmt.Sscan(cgroupRule, &deviceType, &numbers, &permissions); err != nil {
+		return false
+	}
+
+	for _, allowed := range allowlist {
+		var stat unix.Stat_t
+		if err := unix.Stat(allowed.pathOnHost, &stat); err != nil {
+			continue
+		}
+		allowedType := "c"
+		if stat.Mode&unix.S_IFMT == unix.S_IFBLK {
+			allowedType = "b"
+		}
+		rdev := uint64(stat.Rdev)
+		allowedNumbers := fmt.Sprintf("%d:%d", unix.Major(rdev), unix.Minor(rdev))
+		// "a" and "*" give access to much more than one device
+		if deviceType == allowedType && numbers == allowedNumbers && isItSubset(permissions, allowed.permissions) {
+			return true
+		}
+	}
+	return false
+}
+
+func complyTheDeviceRequest(request container.DeviceRequest, allowedRequests []string) bool {
+	for _, allowed := range allowedRequests {
+		if request.Driver != "" && request.Driver == allowed {
+			return true
+		}
+		for _, capabilities := range request.Capabilities {
+			for _, capability := range capabilities {
+				if capability == allowed {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// complyTheDevices checks Devices, DeviceCgroupRules and DeviceRequests
+// against the same allowlist: Devices,"[/dev/fuse,/dev/ttyUSB0:/dev/serial:r,request:gpu]",device,AllowToUse.
+// The container path can be a glob, permissions are the most what can be given.
+// "[]" forbids all devices
+func complyTheDevices(body string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	allowlist, allowedRequests := parseDeviceAllowlist(valueFromCSV)
+
+	for _, resources := range config.resources() {
+		for _, device := range resources.Devices {
+			if !complyTheDeviceMapping(device, allowlist) {
+				return false
+			}
+		}
+		for _, cgroupRule := range resources.DeviceCgroupRules {
+			if !complyTheCgroupRule(cgroupRule, allowlist) {
+				return false
+			}
+		}
+		for _, request := range resources.DeviceRequests {
+			if !complyTheDeviceRequest(request, allowedRequests) {
+				return false
+			}
+		}
+	}
+	return true
+}
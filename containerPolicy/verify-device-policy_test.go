@@ -0,0 +1,156 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func deviceBody(hostConfig map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"Image":      "alpine",
+		"HostConfig": hostConfig,
+	}
+}
+
+func TestComplyTheDevices(t *testing.T) {
+	PathToThePolicy = "testdata/device_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "data"}
+
+	testCases := []AdmitTestCase{
+		{
+			name:   "No devices",
+			body:   deviceBody(map[string]interface{}{"Devices": []interface{}{}}),
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Device for the team without allowlist",
+			body: deviceBody(map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/fuse", "PathInContainer": "/dev/fuse", "CgroupPermissions": "rwm"}},
+			}),
+			caller: roman,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name: "Allowed device",
+			body: deviceBody(map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/fuse", "PathInContainer": "", "CgroupPermissions": ""}},
+			}),
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name: "Allowed device at other path",
+			body: deviceBody(map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/fuse", "PathInContainer": "/dev/sda", "CgroupPermissions": "rwm"}},
+			}),
+			caller: anna,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name: "Container path matches the glob",
+			body: deviceBody(map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/ttyUSB0", "PathInContainer": "/dev/serial0", "CgroupPermissions": "r"}},
+			}),
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name: "Too many permissions",
+			body: deviceBody(map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/ttyUSB0", "PathInContainer": "/dev/serial0", "CgroupPermissions": "rwm"}},
+			}),
+			caller: anna,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name:   "Cgroup rule of allowed device",
+			body:   deviceBody(map[string]interface{}{"DeviceCgroupRules": []string{"c 1:3 r"}}),
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Cgroup rule with too many permissions",
+			body:   deviceBody(map[string]interface{}{"DeviceCgroupRules": []string{"c 1:3 rw"}}),
+			caller: anna,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name:   "Cgroup rule for all devices",
+			body:   deviceBody(map[string]interface{}{"DeviceCgroupRules": []string{"c *:* rwm"}}),
+			caller: anna,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name: "Allowed gpus",
+			body: deviceBody(map[string]interface{}{
+				"DeviceRequests": []map[string]interface{}{{"Driver": "", "Count": -1, "Capabilities": [][]string{{"gpu"}}}},
+			}),
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name: "Gpus without allowlist",
+			body: deviceBody(map[string]interface{}{
+				"DeviceRequests": []map[string]interface{}{{"Driver": "", "Count": -1, "Capabilities": [][]string{{"gpu"}}}},
+			}),
+			caller: roman,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name: "Device at update",
+			body: map[string]interface{}{
+				"Devices": []map[string]string{{"PathOnHost": "/dev/fuse", "PathInContainer": "/dev/fuse", "CgroupPermissions": "rwm"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "devices"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestDevicesOfRawBody(t *testing.T) {
+	PathToThePolicy = "testdata/device_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []struct {
+		name   string
+		body   string
+		result Result
+	}{
+		{
+			name:   "Escaped quotes don't hide the devices",
+			body:   `{"Image":"alpine","HostConfig":{"Devices":[{"PathOnHost":"/dev/sda","PathInContainer":"/dev/sda","CgroupPermissions":"rwm"}]},"X":"%22,%22HostConfig%22:null,%22Y%22:%22"}`,
+			result: Result{answer: false, msg: "devices"},
+		},
+		{
+			name:   "Duplicate HostConfig",
+			body:   `{"Image":"alpine","HostConfig":{"Devices":[{"PathOnHost":"/dev/sda","PathInContainer":"/dev/sda","CgroupPermissions":"rwm"}]},"hostconfig":{}}`,
+			result: Result{answer: false, msg: "Error decoding the body: the key hostconfig is twice in the object"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheContainerPolicy(testCase.body, roman)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
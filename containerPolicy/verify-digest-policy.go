@@ -0,0 +1,76 @@
+package containerpolicy
+
+import (
+	"encoding/csv"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+var (
+	PathToTheDigestDenyList = "containerPolicy/digest_denylist.csv"
+)
+
+// complyTheDigestPinning checks the image is pinned: alpine@sha256:...
+// Digest,true,digest,Required. The rule of the more specific user or team can turn it off with false
+func complyTheDigestPinning(named reference.Named, kindOfPolicy string, valueFromCSV string) bool {
+	if kindOfPolicy != Required {
+		log.Println("I don't know this digest policy!")
+		return false
+	}
+	if valueFromCSV != "true" {
+		return true
+	}
+	_, pinned := named.(reference.Canonical)
+	return pinned
+}
+
+// DigestFromImage returns the digest of the pinned image and empty string for the tag
+func DigestFromImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return ""
+	}
+	if canonical, pinned := named.(reference.Canonical); pinned {
+		return canonical.Digest().String()
+	}
+	return ""
+}
+
+// ComplyTheDigestDenyList checks none of digests is flagged by the scanner.
+// digest_denylist.csv looks like: <sha256:...>,<reason>.
+// Return false and the digest with the reason if one of them is flagged
+func ComplyTheDigestDenyList(digests []string) (bool, string) {
+	file, err := os.Open(PathToTheDigestDenyList)
+	if err != nil {
+		return false, "Error opening the file: " + err.Error()
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// reason is optional
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return false, "Error reading CSV: " + err.Error()
+	}
+
+	for _, row := range records {
+		flagged := strings.ToLower(strings.TrimSpace(row[0]))
+		if flagged == "" {
+			continue
+		}
+		for _, digest := range digests {
+			if strings.ToLower(digest) != flagged {
+				continue
+			}
+			if len(row) > 1 && row[1] != "" {
+				return false, flagged + " (" + row[1] + ")"
+			}
+			return false, flagged
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,51 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheDigestDenyList(t *testing.T) {
+	PathToTheDigestDenyList = "testdata/digest_denylist.csv"
+
+	testCases := []struct {
+		name    string
+		digests []string
+		result  Result
+	}{
+		{
+			name:    "Clean image",
+			digests: []string{"sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"},
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Image without digests",
+			digests: nil,
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Flagged digest with the reason",
+			digests: []string{"sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+			result:  Result{answer: false, msg: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 (CVE-2024-3094 xz backdoor)"},
+		},
+		{
+			name:    "Flagged digest without the reason",
+			digests: []string{"SHA256:486EA46224D1BB4FB680F34F7C9AD96A8F24EC88BE73EA8E5A6C65260E9CB8A7"},
+			result:  Result{answer: false, msg: "sha256:486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheDigestDenyList(testCase.digests)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestDigestFromImage(t *testing.T) {
+	assert.Equal(t, "", DigestFromImage("alpine:3.19"))
+	assert.Equal(t, "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		DigestFromImage("alpine@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"))
+}
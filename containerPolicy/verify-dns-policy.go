@@ -0,0 +1,114 @@
+package containerpolicy
+
+import (
+	"log"
+	"net"
+	"path"
+	"strings"
+)
+
+const (
+	dnsType = "dns"
+)
+
+// matchHostOrAddress matches the value against the entry of the policy.
+// The entry can be a CIDR (10.0.0.0/8), an address or a glob of names (*.internal)
+func matchHostOrAddress(entry string, value string) bool {
+	if _, subnet, err := net.ParseCIDR(entry); err == nil {
+		ip := net.ParseIP(value)
+		return ip != nil && subnet.Contains(ip)
+	}
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.Equal(net.ParseIP(value))
+	}
+	match, _ := path.Match(entry, value)
+	return match
+}
+
+// valuesOfExtraHosts gives both the name and the address of every host:ip or host=ip
+func valuesOfExtraHosts(extraHosts []string) []string {
+	var values []string
+	for _, extraHost := range extraHosts {
+		separator := ":"
+		if strings.Contains(extraHost, "=") {
+			separator = "="
+		}
+		values = append(values, strings.SplitN(extraHost, separator, 2)...)
+	}
+	return values
+}
+
+// valuesOfDNSField returns the values of the field the rule is written for.
+// ExtraHosts gives both the name and the address of every host:ip
+func valuesOfDNSField(config containerBody, nameOfKey string) ([]string, bool) {
+	hostConfig := config.hostConfig()
+	var values []string
+	switch nameOfKey {
+	case "dns":
+		values = hostConfig.DNS
+	case "dnssearch":
+		values = hostConfig.DNSSearch
+	case "dnsoptions":
+		values = hostConfig.DNSOptions
+	case "extrahosts":
+		values = valuesOfExtraHosts(hostConfig.ExtraHosts)
+	case "hostname":
+		if config.Hostname != "" {
+			values = []string{config.Hostname}
+		}
+	case "domainname":
+		if config.Domainname != "" {
+			values = []string{config.Domainname}
+		}
+	default:
+		return nil, false
+	}
+	return values, true
+}
+
+// complyTheDNS checks Dns, DnsSearch, DnsOptions, ExtraHosts, Hostname and Domainname:
+// 1) AllowToUse, if some of valueFromBody doesn't match any of valueFromPolitic - DENY
+// 2) DoesntExpectToSee, if some of valueFromBody matches one of valueFromPolitic - DENY
+func complyTheDNS(body string, nameOfKey string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	values, found := valuesOfDNSField(config, nameOfKey)
+	if !found {
+		log.Println("I don't know this dns field:", nameOfKey)
+		return false
+	}
+	return complyTheHostsOrAddresses(values, kindOfPolicy, valueFromCSV)
+}
+
+// complyTheHostsOrAddresses matches every value against the entries of the policy, see matchHostOrAddress
+func complyTheHostsOrAddresses(values []string, kindOfPolicy string, valueFromCSV string) bool {
+	entries := sliceFromPolicy(valueFromCSV)
+
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		isItMatched := false
+		for _, entry := range entries {
+			if matchHostOrAddress(entry, value) {
+				isItMatched = true
+				break
+			}
+		}
+
+		switch kindOfPolicy {
+		case AllowToUse:
+			if !isItMatched {
+				return false
+			}
+		case DoesntExpectToSee:
+			if isItMatched {
+				return false
+			}
+		default:
+			log.Println("I don't know this dns policy!")
+			return false
+		}
+	}
+	return true
+}
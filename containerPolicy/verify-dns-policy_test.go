@@ -0,0 +1,98 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSRules(t *testing.T) {
+	PathToThePolicy = "testdata/dns_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Good container",
+			body: map[string]interface{}{
+				"Hostname": "roman-api",
+				"HostConfig": map[string]interface{}{
+					"Dns":        []string{"10.0.0.2", "192.168.1.1"},
+					"DnsSearch":  []string{"billing.corp.internal"},
+					"DnsOptions": []string{"ndots:2"},
+					"ExtraHosts": []string{"db.local:172.17.0.5"},
+				},
+			},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name: "Arbitrary resolver",
+			body: map[string]interface{}{
+				"Hostname":   "roman-api",
+				"HostConfig": map[string]interface{}{"Dns": []string{"8.8.8.8"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "dns"},
+		},
+		{
+			name: "Foreign search domain",
+			body: map[string]interface{}{
+				"Hostname":   "roman-api",
+				"HostConfig": map[string]interface{}{"DnsSearch": []string{"evil.example.com"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "dnssearch"},
+		},
+		{
+			name: "Forbidden dns option",
+			body: map[string]interface{}{
+				"Hostname":   "roman-api",
+				"HostConfig": map[string]interface{}{"DnsOptions": []string{"debug"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "dnsoptions"},
+		},
+		{
+			name: "Redirect the registry",
+			body: map[string]interface{}{
+				"Hostname":   "roman-api",
+				"HostConfig": map[string]interface{}{"ExtraHosts": []string{"registry.corp.internal:172.17.0.5"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "extrahosts"},
+		},
+		{
+			name: "Redirect to the metadata endpoint",
+			body: map[string]interface{}{
+				"Hostname":   "roman-api",
+				"HostConfig": map[string]interface{}{"ExtraHosts": []string{"api.local=169.254.169.254"}},
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "extrahosts"},
+		},
+		{
+			name: "Hostname of other user",
+			body: map[string]interface{}{
+				"Hostname": "anna-api",
+			},
+			caller: roman,
+			result: Result{answer: false, msg: "hostname"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
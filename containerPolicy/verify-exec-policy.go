@@ -0,0 +1,134 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	envType     = "env"
+	workdirType = "workdir"
+)
+
+var (
+	PathToTheExecPolicy = "containerPolicy/exec_policy.csv"
+)
+
+// boolOfExec returns the flag of the exec the rule is written for
+func boolOfExec(config types.ExecConfig, nameOfKey string) (bool, bool) {
+	switch nameOfKey {
+	case "privileged":
+		return config.Privileged, true
+	case "attachstdin":
+		return config.AttachStdin, true
+	case "attachstdout":
+		return config.AttachStdout, true
+	case "attachstderr":
+		return config.AttachStderr, true
+	case "tty":
+		return config.Tty, true
+	case "detach":
+		return config.Detach, true
+	}
+	return false, false
+}
+
+// complyTheEnv checks names of Env. ForbiddenKeys - the name must not match
+// any of globs from politic (LD_*), AllowToUse - the name must match one of them
+func complyTheEnv(env []string, kindOfPolicy string, valueFromCSV string) bool {
+	for _, variable := range env {
+		name := strings.ToLower(strings.SplitN(variable, "=", 2)[0])
+		isItMatched := false
+		for _, pattern := range sliceFromPolicy(valueFromCSV) {
+			if match, _ := path.Match(pattern, name); match {
+				isItMatched = true
+				break
+			}
+		}
+
+		switch kindOfPolicy {
+		case ForbiddenKeys:
+			if isItMatched {
+				return false
+			}
+		case AllowToUse:
+			if !isItMatched {
+				return false
+			}
+		default:
+			log.Println("I don't know this env policy!")
+			return false
+		}
+	}
+	return true
+}
+
+// complyTheWorkingDir checks WorkingDir is one of directories from politic or inside of it.
+// Empty WorkingDir is the directory of the container
+func complyTheWorkingDir(workingDir string, valueFromCSV string) bool {
+	if workingDir == "" {
+		return true
+	}
+	return isItAllowedHostPath(path.Clean(strings.ToLower(workingDir)), sliceFromPolicy(valueFromCSV))
+}
+
+// Policy for POST /containers/{id}/exec. The rules live at exec_policy.csv
+// and can be scoped by the caller and the image of the container:
+// 1) Privileged, AttachStdin, Tty and others with type "bool" - ExpectToSee
+// 2) User with type "user" - NonRoot, AllowToUse, DoesntExpectToSee, the uid without the group as the number
+// 3) Env with type "env" - ForbiddenKeys, AllowToUse for names of variables
+// 4) WorkingDir with type "workdir" - AllowToUse
+// 5) Cmd with type "command" - DoesntExpectToSee, AllowToUse like Command of the container policy
+func ComplyTheExecPolicy(body string, caller identity.Identity, image string) (bool, string) {
+	rules, err := LoadRules(PathToTheExecPolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	var config types.ExecConfig
+	if err := decodeBody(body, &config); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+		kindOfPolicy := rule.Kind
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case "bool":
+			valueFromBody, found := boolOfExec(config, nameOfKey)
+			if !found || kindOfPolicy != ExpectToSee {
+				log.Println("I don't know this exec policy:", rule.Key, kindOfPolicy)
+				return false, rule.Name()
+			}
+			yes = strconv.FormatBool(valueFromBody) == valueFromCSV
+		case userType:
+			yes = complyTheUserName(config.User, kindOfPolicy, valueFromCSV)
+		case envType:
+			yes = complyTheEnv(config.Env, kindOfPolicy, valueFromCSV)
+		case workdirType:
+			yes = complyTheWorkingDir(config.WorkingDir, valueFromCSV)
+		case commandType:
+			yes = complyTheCommandLines(config.Cmd, kindOfPolicy, valueFromCSV)
+		default:
+			log.Println("I don't know this exec policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,139 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type ExecTestCase struct {
+	name   string
+	body   map[string]interface{}
+	caller identity.Identity
+	image  string
+	result Result
+}
+
+func TestComplyTheExecPolicy(t *testing.T) {
+	PathToTheExecPolicy = "testdata/exec_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	oleg := identity.Identity{KeyHash: "3b4c5d6e", User: "oleg", UID: "1002", Team: "ops"}
+
+	testCases := []ExecTestCase{
+		{
+			name:   "Good exec",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "AttachStdin": true, "Tty": true, "WorkingDir": "/app/src"},
+			caller: roman,
+			image:  "alpine",
+			result: Result{true, ""},
+		},
+		{
+			name:   "Privileged exec",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "Privileged": true},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "privileged"},
+		},
+		{
+			name:   "Exec as root",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "User": "0"},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Exec as root with the leading zero",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "User": "00"},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Exec as root with the group",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "User": "0:0"},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Ops team can exec as root",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "User": "root:root"},
+			caller: oleg,
+			image:  "alpine",
+			result: Result{true, ""},
+		},
+		{
+			name:   "Forbidden env",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "Env": []string{"TERM=xterm", "LD_PRELOAD=/tmp/x.so"}},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "env"},
+		},
+		{
+			name:   "Working dir outside of allowed",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "WorkingDir": "/app/../etc"},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "workingdir"},
+		},
+		{
+			name:   "Read the shadow",
+			body:   map[string]interface{}{"Cmd": []string{"cat", "/etc/shadow"}},
+			caller: roman,
+			image:  "alpine",
+			result: Result{answer: false, msg: "cmd"},
+		},
+		{
+			name:   "Interactive exec at production image",
+			body:   map[string]interface{}{"Cmd": []string{"sh"}, "AttachStdin": true},
+			caller: roman,
+			image:  "registry.corp.internal/prod/billing",
+			result: Result{answer: false, msg: "attachstdin"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheExecPolicy(string(jsonString), testCase.caller, testCase.image)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestExecOfRawBody(t *testing.T) {
+	PathToTheExecPolicy = "testdata/exec_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []struct {
+		name   string
+		body   string
+		result Result
+	}{
+		{
+			name:   "Escaped quotes don't hide Privileged",
+			body:   `{"Cmd":["sh"],"Privileged":true,"X":"%22,%22Privileged%22:null,%22Y%22:%22"}`,
+			result: Result{answer: false, msg: "privileged"},
+		},
+		{
+			name:   "Duplicate Privileged",
+			body:   `{"Cmd":["sh"],"Privileged":true,"privileged":null}`,
+			result: Result{answer: false, msg: "Error decoding the body: the key privileged is twice in the object"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheExecPolicy(testCase.body, roman, "alpine")
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
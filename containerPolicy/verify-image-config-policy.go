@@ -0,0 +1,181 @@
+package containerpolicy
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+const (
+	ageType          = "age"
+	architectureType = "architecture"
+	volumeType       = "volume"
+)
+
+var (
+	PathToTheImageConfigPolicy = "containerPolicy/image_config_policy.csv"
+)
+
+// effectiveUser is the user the container will run as: User of the body beats USER of the image
+func effectiveUser(config containerBody, imageConfig container.Config) string {
+	if config.User != "" {
+		return config.User
+	}
+	return imageConfig.User
+}
+
+// complyTheImageLabels checks the labels of the image, the container can't fake them:
+// 1) Labels,"[org.opencontainers.image.source]",labels,RequiredKeys, if some of keys is absent - DENY
+// 2) Labels.<key>,"^https://github.com/corp/",label,MatchRegexp, if the label is absent or doesn't match - DENY
+func complyTheImageLabels(labels map[string]string, nameOfKey string, kindOfPolicy string, valueFromCSV string) (bool, string) {
+	switch kindOfPolicy {
+	case RequiredKeys:
+		for _, required := range sliceFromPolicy(valueFromCSV) {
+			if _, found := labels[required]; !found {
+				return false, "labels." + required
+			}
+		}
+		return true, ""
+	case MatchRegexp:
+		labelKey := strings.TrimPrefix(nameOfKey, "Labels.")
+		re, err := regexp.Compile(valueFromCSV)
+		if err != nil {
+			log.Println("Wrong regexp at the image config policy:", valueFromCSV)
+			return false, "labels." + labelKey
+		}
+		if !re.MatchString(labels[labelKey]) {
+			return false, "labels." + labelKey
+		}
+		return true, ""
+	}
+	log.Println("I don't know this image label policy!")
+	return false, strings.ToLower(nameOfKey)
+}
+
+// complyTheImageAge checks the image is not older than valueFromPolitic: Created,2160h,age,MaxValue.
+// The image without the date is too old
+func complyTheImageAge(created string, kindOfPolicy string, valueFromCSV string) bool {
+	if kindOfPolicy != MaxValue {
+		log.Println("I don't know this age policy!")
+		return false
+	}
+	maxAge, err := time.ParseDuration(valueFromCSV)
+	if err != nil {
+		log.Println("Wrong MaxValue at the age policy:", valueFromCSV)
+		return false
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		return false
+	}
+	return time.Since(createdAt) <= maxAge
+}
+
+// complyTheExposedPorts checks the ports exposed by the image and by the body
+// are inside of the range from politic: ExposedPorts,1024-65535,portrange,AllowToUse
+func complyTheExposedPorts(config containerBody, imageConfig container.Config, valueFromCSV string) bool {
+	allowedFirst, allowedLast, ok := parsePortRange(valueFromCSV)
+	if !ok {
+		return false
+	}
+	for _, exposedPorts := range []nat.PortSet{config.ExposedPorts, imageConfig.ExposedPorts} {
+		for port := range exposedPorts {
+			// 80/tcp or 8000-8005/tcp
+			first, last, ok := parsePortRange(port.Port())
+			if !ok || first < allowedFirst || last > allowedLast {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// complyTheVolumes checks the anonymous volumes of the image and of the body:
+// 1) DoesntExpectToSee, if the volume is one of paths from politic or inside of it - DENY
+// 2) AllowToUse, if the volume is not inside of paths from politic - DENY
+func complyTheVolumes(config containerBody, imageConfig container.Config, kindOfPolicy string, valueFromCSV string) bool {
+	paths := sliceFromPolicy(valueFromCSV)
+	for _, volumes := range []map[string]struct{}{config.Volumes, imageConfig.Volumes} {
+		for volume := range volumes {
+			isItMatched := isItAllowedHostPath(normalizeContainerPath(volume), paths)
+			switch kindOfPolicy {
+			case DoesntExpectToSee:
+				if isItMatched {
+					return false
+				}
+			case AllowToUse:
+				if !isItMatched {
+					return false
+				}
+			default:
+				log.Println("I don't know this volume policy!")
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Policy for the config of the image the container is created from. The rules live at image_config_policy.csv,
+// image is what docker image inspect returns and it is judged together with the create body:
+// 1) User with type "user" - NonRoot, DoesntExpectToSee, AllowToUse for User of the body or USER of the image
+// 2) Labels with type "labels" and Labels.<key> with type "label" - the labels of the image
+// 3) Created with type "age" - MaxValue, the duration like 720h
+// 4) Architecture with type "architecture" - AllowToUse
+// 5) ExposedPorts with type "portrange" - AllowToUse for EXPOSE of the image and ExposedPorts of the body
+// 6) Volumes with type "volume" - DoesntExpectToSee, AllowToUse for VOLUME of the image and Volumes of the body
+func ComplyTheImageConfigPolicy(body string, image types.ImageInspect, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheImageConfigPolicy, ScopeFromBody(body, caller))
+	if err != nil {
+		return false, err.Error()
+	}
+
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+	var imageConfig container.Config
+	if image.Config != nil {
+		imageConfig = *image.Config
+	}
+
+	for _, rule := range rules {
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+
+		var yes bool
+		switch rule.Type {
+		case userType:
+			yes = complyTheUserName(effectiveUser(config, imageConfig), rule.Kind, strings.ToLower(valueFromCSV))
+		case labelsType, labelType:
+			// keys of labels are case-sensitive
+			if yes, failedPolicy := complyTheImageLabels(imageConfig.Labels, rule.Key, rule.Kind, valueFromCSV); !yes {
+				return false, failedPolicy
+			}
+			continue
+		case ageType:
+			yes = complyTheImageAge(image.Created, rule.Kind, strings.ToLower(valueFromCSV))
+		case architectureType:
+			yes = complyTheImageSource(strings.ToLower(image.Architecture), rule.Kind, strings.ToLower(valueFromCSV))
+		case portRangeType:
+			yes = complyTheExposedPorts(config, imageConfig, valueFromCSV)
+		case volumeType:
+			yes = complyTheVolumes(config, imageConfig, rule.Kind, strings.ToLower(valueFromCSV))
+		default:
+			log.Println("I don't know this image config policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
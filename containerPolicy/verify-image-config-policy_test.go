@@ -0,0 +1,137 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+)
+
+type ImageConfigTestCase struct {
+	name   string
+	body   map[string]interface{}
+	image  types.ImageInspect
+	result Result
+}
+
+// imageOf is the fresh amd64 image of the corp with the config
+func imageOf(config container.Config) types.ImageInspect {
+	if config.Labels == nil {
+		config.Labels = map[string]string{"org.opencontainers.image.source": "https://github.com/corp/api"}
+	}
+	return types.ImageInspect{
+		Created:      time.Now().Add(-24 * time.Hour).Format(time.RFC3339Nano),
+		Architecture: "amd64",
+		Config:       &config,
+	}
+}
+
+func TestComplyTheImageConfigPolicy(t *testing.T) {
+	PathToTheImageConfigPolicy = "testdata/image_config_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	oldImage := imageOf(container.Config{User: "app"})
+	oldImage.Created = time.Now().Add(-24 * 365 * time.Hour).Format(time.RFC3339Nano)
+	armImage := imageOf(container.Config{User: "app"})
+	armImage.Architecture = "386"
+
+	testCases := []ImageConfigTestCase{
+		{
+			name:   "Image with the user",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app"}),
+			result: Result{true, ""},
+		},
+		{
+			name:   "Image with USER root",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "root"}),
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Image without USER",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{}),
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Body overrides USER root",
+			body:   map[string]interface{}{"Image": "api", "User": "1000:1000"},
+			image:  imageOf(container.Config{User: "root"}),
+			result: Result{true, ""},
+		},
+		{
+			name:   "Body runs the image as root",
+			body:   map[string]interface{}{"Image": "api", "User": "0"},
+			image:  imageOf(container.Config{User: "app"}),
+			result: Result{answer: false, msg: "user"},
+		},
+		{
+			name:   "Image without the source",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app", Labels: map[string]string{"maintainer": "corp"}}),
+			result: Result{answer: false, msg: "labels.org.opencontainers.image.source"},
+		},
+		{
+			name:   "Image from other source",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app", Labels: map[string]string{"org.opencontainers.image.source": "https://github.com/evil/api"}}),
+			result: Result{answer: false, msg: "labels.org.opencontainers.image.source"},
+		},
+		{
+			name:   "Old image",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  oldImage,
+			result: Result{answer: false, msg: "created"},
+		},
+		{
+			name:   "Image of other architecture",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  armImage,
+			result: Result{answer: false, msg: "architecture"},
+		},
+		{
+			name:   "Image exposes privileged port",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app", ExposedPorts: nat.PortSet{"80/tcp": {}}}),
+			result: Result{answer: false, msg: "exposedports"},
+		},
+		{
+			name:   "Body exposes privileged port",
+			body:   map[string]interface{}{"Image": "api", "ExposedPorts": map[string]interface{}{"22/tcp": map[string]interface{}{}}},
+			image:  imageOf(container.Config{User: "app", ExposedPorts: nat.PortSet{"8080/tcp": {}}}),
+			result: Result{answer: false, msg: "exposedports"},
+		},
+		{
+			name:   "Image declares dangerous volume",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app", Volumes: map[string]struct{}{"/var/lib/docker": {}}}),
+			result: Result{answer: false, msg: "volumes"},
+		},
+		{
+			name:   "Image declares data volume",
+			body:   map[string]interface{}{"Image": "api"},
+			image:  imageOf(container.Config{User: "app", Volumes: map[string]struct{}{"/data": {}}}),
+			result: Result{true, ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheImageConfigPolicy(string(jsonString), testCase.image, roman)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	registryType   = "registry"
+	repositoryType = "repository"
+	digestType     = "digest"
+	pushType       = "push"
+)
+
+var (
+	PathToTheImagePolicy = "containerPolicy/image_policy.csv"
+	// docker daemon takes sha256:<id> and any prefix of the id as the image,
+	// even if it looks like the name of docker hub
+	imageIDRegexp = regexp.MustCompile(`^(sha256:)?[a-f0-9]+$`)
+)
+
+// ImageFromPull puts fromImage and tag of /images/create together,
+// tag can be the digest: fromImage=alpine&tag=sha256:...
+func ImageFromPull(fromImage string, tag string) string {
+	if tag == "" {
+		return fromImage
+	}
+	if strings.Contains(tag, ":") {
+		return fromImage + "@" + tag
+	}
+	return fromImage + ":" + tag
+}
+
+// complyTheImageSource checks the registry or the repository of the image:
+// 1) AllowToUse, if it doesn't match any of globs from politic - DENY
+// 2) DoesntExpectToSee, if it matches one of globs from politic - DENY.
+// "*" of the glob matches "/" too, so registry.corp.internal/* is the whole registry
+func complyTheImageSource(source string, kindOfPolicy string, valueFromCSV string) bool {
+	isItMatched := false
+	for _, pattern := range sliceFromPolicy(valueFromCSV) {
+		if globToRegexp(pattern).MatchString(source) {
+			isItMatched = true
+			break
+		}
+	}
+
+	switch kindOfPolicy {
+	case AllowToUse:
+		return isItMatched
+	case DoesntExpectToSee:
+		return !isItMatched
+	}
+	log.Println("I don't know this image policy!")
+	return false
+}
+
+// Policy for the source of images, it is checked at the pull (/images/create?fromImage=)
+// and at the Image of the create body, so the cached images are judged too. The rules live at image_policy.csv:
+// 1) Registry with type "registry" - the registry of the normalized reference (docker.io for alpine)
+// 2) Repository with type "repository" - the whole name (docker.io/library/alpine)
+// 3) Digest with type "digest" - Required, see complyTheDigestPinning
+// Image ID can't be judged without docker daemon, it is denied, if there are any rules.
+// The labels of scope are the labels of the container, the pull doesn't have them
+func ComplyTheImagePolicy(image string, scope Scope) (bool, string) {
+	caller := scope.Caller
+	rules, err := LoadRules(PathToTheImagePolicy, scope)
+	if err != nil {
+		return false, err.Error()
+	}
+	// push rules are for ComplyThePushPolicy
+	var sourceRules []Rule
+	for _, rule := range rules {
+		if rule.Type != pushType {
+			sourceRules = append(sourceRules, rule)
+		}
+	}
+	if len(sourceRules) == 0 {
+		return true, ""
+	}
+
+	if imageIDRegexp.MatchString(image) {
+		return false, "image"
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		log.Println("Error parsing the image:", err)
+		return false, "image"
+	}
+	registry := reference.Domain(named)
+	repository := named.Name()
+
+	for _, rule := range sourceRules {
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case registryType:
+			yes = complyTheImageSource(registry, rule.Kind, valueFromCSV)
+		case repositoryType:
+			yes = complyTheImageSource(repository, rule.Kind, valueFromCSV)
+		case digestType:
+			yes = complyTheDigestPinning(named, rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this image policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
+
+// NormalizeImage turns the image into the whole name with the tag: alpine is docker.io/library/alpine:latest.
+// The image ID and what can't be parsed are returned as they are
+func NormalizeImage(image string) string {
+	if imageIDRegexp.MatchString(image) {
+		return image
+	}
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+// ImagesOfDelete returns what docker daemon removed at /images/{name} DELETE:
+// the normalized names it untagged and the IDs it deleted
+func ImagesOfDelete(body string) []string {
+	var items []types.ImageDeleteResponseItem
+	if err := json.Unmarshal([]byte(body), &items); err != nil {
+		return nil
+	}
+
+	var images []string
+	for _, item := range items {
+		if item.Untagged != "" {
+			images = append(images, NormalizeImage(item.Untagged))
+		}
+		if item.Deleted != "" {
+			images = append(images, item.Deleted)
+		}
+	}
+	return images
+}
+
+// ComplyThePushPolicy checks the pushed image is inside of the namespaces of the caller.
+// The rules with type "push" live at image_policy.csv, "*" matches "/" too:
+// Push,"[registry.corp.internal/${user}/*,registry.corp.internal/${team}/*]",push,AllowToUse
+func ComplyThePushPolicy(image string, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheImagePolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		log.Println("Error parsing the image:", err)
+		return false, "image"
+	}
+
+	for _, rule := range rules {
+		if rule.Type != pushType {
+			continue
+		}
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		if yes := complyTheImageSource(named.Name(), rule.Kind, strings.ToLower(valueFromCSV)); !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,184 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type ImageTestCase struct {
+	name   string
+	image  string
+	caller identity.Identity
+	labels map[string]string
+	result Result
+}
+
+func TestComplyTheImagePolicy(t *testing.T) {
+	PathToTheImagePolicy = "testdata/image_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "data"}
+	ciBot := identity.Identity{KeyHash: "9a1b2c3d", User: "ci-bot", UID: "1100", Team: "ci"}
+
+	testCases := []ImageTestCase{
+		{
+			name:   "Official image",
+			image:  "alpine",
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Official image with the whole name",
+			image:  "docker.io/library/alpine:3.19",
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Image of the corp registry",
+			image:  "registry.corp.internal/prod/api@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Unknown registry",
+			image:  "evil.example.com/library/alpine",
+			caller: anna,
+			result: Result{answer: false, msg: "registry"},
+		},
+		{
+			name:   "Registry with port",
+			image:  "registry.corp.internal:5000/prod/api",
+			caller: anna,
+			result: Result{answer: false, msg: "registry"},
+		},
+		{
+			name:   "Image of docker hub user",
+			image:  "someone/miner",
+			caller: anna,
+			result: Result{answer: false, msg: "repository"},
+		},
+		{
+			name:   "Forbidden repository",
+			image:  "ubuntu:22.04",
+			caller: anna,
+			result: Result{answer: false, msg: "repository"},
+		},
+		{
+			name:   "Repository of the team",
+			image:  "infra/tools:1.0",
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Repository of other team",
+			image:  "data/tools:1.0",
+			caller: roman,
+			result: Result{answer: false, msg: "repository"},
+		},
+		{
+			name:   "Image ID",
+			image:  "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			caller: anna,
+			result: Result{answer: false, msg: "image"},
+		},
+		{
+			name:   "Short image ID",
+			image:  "9f86d081884c",
+			caller: anna,
+			result: Result{answer: false, msg: "image"},
+		},
+		{
+			name:   "Production container from the tag",
+			image:  "registry.corp.internal/prod/api:1.2",
+			caller: anna,
+			labels: map[string]string{"env": "prod"},
+			result: Result{answer: false, msg: "digest"},
+		},
+		{
+			name:   "Production container from the digest",
+			image:  "registry.corp.internal/prod/api:1.2@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			caller: anna,
+			labels: map[string]string{"env": "prod"},
+			result: Result{true, ""},
+		},
+		{
+			name:   "Pull by the bot",
+			image:  "alpine:3.19",
+			caller: ciBot,
+			result: Result{answer: false, msg: "digest"},
+		},
+		{
+			name:   "Production container of the bot from the tag",
+			image:  "alpine:3.19",
+			caller: ciBot,
+			labels: map[string]string{"env": "prod"},
+			result: Result{answer: false, msg: "digest"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheImagePolicy(testCase.image, Scope{Caller: testCase.caller, Image: testCase.image, Labels: testCase.labels})
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestImageFromPull(t *testing.T) {
+	assert.Equal(t, "alpine", ImageFromPull("alpine", ""))
+	assert.Equal(t, "alpine:3.19", ImageFromPull("alpine", "3.19"))
+	assert.Equal(t, "alpine@sha256:9f86d081", ImageFromPull("alpine", "sha256:9f86d081"))
+}
+
+func TestComplyThePushPolicy(t *testing.T) {
+	PathToTheImagePolicy = "testdata/image_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001"}
+
+	testCases := []ImageTestCase{
+		{
+			name:   "Push to the namespace of the user",
+			image:  "registry.corp.internal/roman/api:1.0",
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Push to the namespace of the team",
+			image:  "registry.corp.internal/infra/tools/lint",
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Push to the namespace of other user",
+			image:  "registry.corp.internal/anna/api:1.0",
+			caller: roman,
+			result: Result{answer: false, msg: "push"},
+		},
+		{
+			name:   "Push to docker hub",
+			image:  "roman/api",
+			caller: roman,
+			result: Result{answer: false, msg: "push"},
+		},
+		{
+			name:   "Caller without the team",
+			image:  "registry.corp.internal/anna/api:1.0",
+			caller: anna,
+			result: Result{answer: false, msg: "push"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyThePushPolicy(testCase.image, testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	assert.Equal(t, "docker.io/library/alpine:latest", NormalizeImage("alpine"))
+	assert.Equal(t, "registry.corp.internal/roman/api:1.0", NormalizeImage("registry.corp.internal/roman/api:1.0"))
+	assert.Equal(t, "9f86d081884c", NormalizeImage("9f86d081884c"))
+}
@@ -0,0 +1,236 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-units"
+)
+
+const (
+	MaxValue   = "MaxValue"
+	MinValue   = "MinValue"
+	MaxSize    = "MaxSize"
+	sysctlType = "sysctl"
+	ulimitType = "ulimit"
+	tmpfsType  = "tmpfs"
+	sizeType   = "size"
+	intType    = "int"
+)
+
+// complyTheSysctls checks every key of HostConfig.Sysctls matches one of
+// the namespaced sysctls from politic: Sysctls,"[net.*,kernel.shm*]",sysctl,AllowToUse
+func complyTheSysctls(body string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+
+	for sysctl := range config.hostConfig().Sysctls {
+		isItValueOK := false
+		for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+			if match, _ := path.Match(allowToUse, strings.ToLower(sysctl)); match {
+				isItValueOK = true
+				break
+			}
+		}
+		if !isItValueOK {
+			return false
+		}
+	}
+	return true
+}
+
+// complyTheUlimits checks Ulimits at create and update:
+// 1) Ulimits,"[nofile,nproc]",ulimit,AllowToUse, if the name isn't one of valueFromPolitic - DENY
+// 2) Ulimits.<name>,65536,ulimit,MaxValue, if soft or hard limit > valueFromPolitic - DENY
+func complyTheUlimits(body string, nameOfKey string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+
+	for _, resources := range config.resources() {
+		for _, ulimit := range resources.Ulimits {
+			if ulimit == nil {
+				continue
+			}
+			name := strings.ToLower(ulimit.Name)
+
+			switch kindOfPolicy {
+			case AllowToUse:
+				isItValueOK := false
+				for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+					if allowToUse == name {
+						isItValueOK = true
+						break
+					}
+				}
+				if !isItValueOK {
+					return false
+				}
+			case MaxValue:
+				if nameOfKey != "ulimits."+name {
+					continue
+				}
+				maxValue, err := strconv.ParseInt(valueFromCSV, 10, 64)
+				if err != nil {
+					log.Println("Wrong MaxValue at the ulimit policy:", valueFromCSV)
+					return false
+				}
+				// -1 is unlimited
+				if ulimit.Soft < 0 || ulimit.Hard < 0 || ulimit.Soft > maxValue || ulimit.Hard > maxValue {
+					return false
+				}
+			default:
+				log.Println("I don't know this ulimit policy!")
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseTmpfsOptions turns "rw,noexec,size=64m" into options and the size in bytes,
+// size is -1 if it is not set
+func parseTmpfsOptions(options string) ([]string, int64) {
+	var parsed []string
+	size := int64(-1)
+	for _, option := range strings.Split(strings.ToLower(options), ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		if strings.HasPrefix(option, "size=") {
+			bytes, err := units.RAMInBytes(strings.TrimPrefix(option, "size="))
+			if err != nil {
+				// we can't judge it, so it is too big
+				bytes = 1<<63 - 1
+			}
+			size = bytes
+			continue
+		}
+		parsed = append(parsed, option)
+	}
+	return parsed, size
+}
+
+// complyTheTmpfs checks HostConfig.Tmpfs and tmpfs at HostConfig.Mounts:
+// 1) MaxSize, if size is not set or > valueFromPolitic - DENY
+// 2) Required, if some of options from politic is absent - DENY
+// 3) DoesntExpectToSee, if some of options is one of valueFromPolitic - DENY.
+// Options of tmpfs at Mounts are only the size and the mode, so only MaxSize is checked there
+func complyTheTmpfs(body string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	hostConfig := config.hostConfig()
+
+	for _, options := range hostConfig.Tmpfs {
+		parsed, size := parseTmpfsOptions(options)
+
+		switch kindOfPolicy {
+		case MaxSize:
+			maxSize, err := units.RAMInBytes(valueFromCSV)
+			if err != nil {
+				log.Println("Wrong MaxSize at the tmpfs policy:", valueFromCSV)
+				return false
+			}
+			// without size tmpfs takes the half of RAM
+			if size < 0 || size > maxSize {
+				return false
+			}
+		case Required:
+			for _, required := range sliceFromPolicy(valueFromCSV) {
+				isItValueOK := false
+				for _, option := range parsed {
+					if option == required {
+						isItValueOK = true
+						break
+					}
+				}
+				if !isItValueOK {
+					return false
+				}
+			}
+		case DoesntExpectToSee:
+			for _, dontExpect := range sliceFromPolicy(valueFromCSV) {
+				for _, option := range parsed {
+					if option == dontExpect {
+						return false
+					}
+				}
+			}
+		default:
+			log.Println("I don't know this tmpfs policy!")
+			return false
+		}
+	}
+
+	if kindOfPolicy == MaxSize {
+		maxSize, err := units.RAMInBytes(valueFromCSV)
+		if err != nil {
+			return false
+		}
+		for _, m := range hostConfig.Mounts {
+			if m.Type != mount.TypeTmpfs {
+				continue
+			}
+			if m.TmpfsOptions == nil || m.TmpfsOptions.SizeBytes <= 0 || m.TmpfsOptions.SizeBytes > maxSize {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// numberFromBody returns the numeric field of the body the rule is written for
+func numberFromBody(config containerBody, nameOfKey string) (int64, bool) {
+	hostConfig := config.hostConfig()
+	switch nameOfKey {
+	case "shmsize":
+		return hostConfig.ShmSize, true
+	case "oomscoreadj":
+		return int64(hostConfig.OomScoreAdj), true
+	}
+	return 0, false
+}
+
+// complyTheNumber checks the numeric field is inside of the bounds:
+// ShmSize,256m,size,MaxValue or OomScoreAdj,0,int,MinValue.
+// Zero ShmSize means the default of docker daemon (64m)
+func complyTheNumber(body string, nameOfKey string, typeOfData string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	valueFromBody, found := numberFromBody(config, nameOfKey)
+	if !found {
+		log.Println("I don't know this number:", nameOfKey)
+		return false
+	}
+
+	var bound int64
+	if typeOfData == sizeType {
+		bound, err = units.RAMInBytes(valueFromCSV)
+	} else {
+		bound, err = strconv.ParseInt(valueFromCSV, 10, 64)
+	}
+	if err != nil {
+		log.Println("Wrong value at the policy:", valueFromCSV)
+		return false
+	}
+
+	switch kindOfPolicy {
+	case MaxValue:
+		return valueFromBody <= bound
+	case MinValue:
+		return valueFromBody >= bound
+	}
+	log.Println("I don't know this number policy!")
+	return false
+}
@@ -0,0 +1,113 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelRules(t *testing.T) {
+	PathToThePolicy = "testdata/kernel_policy.csv"
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Good container",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"HostConfig": map[string]interface{}{
+					"Sysctls":     map[string]string{"net.ipv4.ip_forward": "1"},
+					"Ulimits":     []map[string]interface{}{{"Name": "nofile", "Soft": 1024, "Hard": 65536}},
+					"Tmpfs":       map[string]string{"/run": "rw,noexec,nosuid,size=65536k"},
+					"ShmSize":     67108864,
+					"OomScoreAdj": 500,
+				},
+			},
+			result: Result{true, ""},
+		},
+		{
+			name: "Not namespaced sysctl",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"Sysctls": map[string]string{"kernel.panic": "1"},
+				},
+			},
+			result: Result{answer: false, msg: "sysctls"},
+		},
+		{
+			name: "Unknown ulimit",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"Ulimits": []map[string]interface{}{{"Name": "memlock", "Soft": -1, "Hard": -1}},
+				},
+			},
+			result: Result{answer: false, msg: "ulimits"},
+		},
+		{
+			name: "Ulimit above the ceiling at update",
+			body: map[string]interface{}{
+				"Ulimits": []map[string]interface{}{{"Name": "nofile", "Soft": 1024, "Hard": 1048576}},
+			},
+			result: Result{answer: false, msg: "ulimits.nofile"},
+		},
+		{
+			name: "Tmpfs without size",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"Tmpfs": map[string]string{"/run": "rw,noexec,nosuid"},
+				},
+			},
+			result: Result{answer: false, msg: "tmpfs"},
+		},
+		{
+			name: "Tmpfs without noexec",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"Tmpfs": map[string]string{"/run": "rw,nosuid,size=1m"},
+				},
+			},
+			result: Result{answer: false, msg: "tmpfs"},
+		},
+		{
+			name: "Too big tmpfs mount",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"Mounts": []map[string]interface{}{{"Type": "tmpfs", "Target": "/run", "TmpfsOptions": map[string]interface{}{"SizeBytes": 1073741824}}},
+				},
+			},
+			result: Result{answer: false, msg: "tmpfs"},
+		},
+		{
+			name: "Too big shm",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"ShmSize": 1073741824,
+				},
+			},
+			result: Result{answer: false, msg: "shmsize"},
+		},
+		{
+			name: "Container protected from OOM killer",
+			body: map[string]interface{}{
+				"HostConfig": map[string]interface{}{
+					"OomScoreAdj": -1000,
+				},
+			},
+			result: Result{answer: false, msg: "oomscoreadj"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+)
+
+const (
+	RequiredKeys  = "RequiredKeys"
+	ForbiddenKeys = "ForbiddenKeys"
+	MaxCount      = "MaxCount"
+	MatchRegexp   = "MatchRegexp"
+	labelsType    = "labels"
+	labelType     = "label"
+)
+
+// sliceFromPolicy turns "[value1,value2]" from the CSV into a slice
+func sliceFromPolicy(valueFromCSV string) []string {
+	csv := strings.Trim(valueFromCSV, "[]")
+	if csv == "" {
+		return nil
+	}
+	return strings.Split(csv, ",")
+}
+
+// Label policy for creation container. Rows with type "labels" check the whole Labels map:
+// 1) RequiredKeys, if some of keyFromPolitic is absent - DENY
+// 2) ForbiddenKeys, if some of key matches a pattern from politic (com.docker.compose.*) - DENY
+// 3) MaxCount, if count of labels > valueFromPolitic - DENY
+// Rows with type "label" and name Labels.<key> check the value of a single label:
+// 1) AllowToUse, if value isn't one of valueFromPolitic - DENY
+// 2) MatchRegexp, if value doesn't match the regexp - DENY
+// Absent label is not checked by "label" rows, use RequiredKeys for it.
+// valueFromPolitic can use the caller: Labels.owner,"[${user}]",label,AllowToUse
+func ComplyTheLabelPolicy(body string, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToThePolicy, ScopeFromBody(body, caller))
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// Docker daemon decodes the body the same way, so we judge what it will really get
+	var config struct {
+		Labels map[string]string
+	}
+	if err := decodeBody(body, &config); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+
+	for _, rule := range rules {
+		nameOfKey := rule.Key
+		typeOfData := rule.Type
+		kindOfPolicy := rule.Kind
+
+		if typeOfData != labelsType && typeOfData != labelType {
+			continue
+		}
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, strings.ToLower(nameOfKey)
+		}
+
+		switch typeOfData {
+		case labelsType:
+			switch kindOfPolicy {
+			case RequiredKeys:
+				for _, required := range sliceFromPolicy(valueFromCSV) {
+					if _, found := config.Labels[required]; !found {
+						return false, "labels." + required
+					}
+				}
+			case ForbiddenKeys:
+				for key := range config.Labels {
+					for _, forbidden := range sliceFromPolicy(valueFromCSV) {
+						if match, _ := path.Match(forbidden, key); match {
+							return false, "labels." + key
+						}
+					}
+				}
+			case MaxCount:
+				maxCount, err := strconv.Atoi(valueFromCSV)
+				if err != nil {
+					log.Println("Wrong MaxCount at the label policy:", valueFromCSV)
+					return false, "labels"
+				}
+				if len(config.Labels) > maxCount {
+					return false, "labels"
+				}
+			default:
+				log.Println("I don't know this label policy!")
+			}
+		case labelType:
+			labelKey := strings.TrimPrefix(nameOfKey, "Labels.")
+			value, found := config.Labels[labelKey]
+			if !found {
+				continue
+			}
+
+			switch kindOfPolicy {
+			case AllowToUse:
+				isItValueOK := false
+				for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+					if allowToUse == value {
+						isItValueOK = true
+						break
+					}
+				}
+				if !isItValueOK {
+					return false, "labels." + labelKey
+				}
+			case MatchRegexp:
+				re, err := regexp.Compile(valueFromCSV)
+				if err != nil {
+					log.Println("Wrong regexp at the label policy:", valueFromCSV)
+					return false, "labels." + labelKey
+				}
+				if !re.MatchString(value) {
+					return false, "labels." + labelKey
+				}
+			default:
+				log.Println("I don't know this label policy!")
+			}
+		}
+	}
+	return true, ""
+}
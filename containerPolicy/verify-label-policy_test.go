@@ -0,0 +1,111 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheLabelPolicy(t *testing.T) {
+	PathToThePolicy = "testdata/label_policy.csv"
+
+	testCases := []AdmitTestCase{
+		{
+			name: "All labels are in place",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":    "infra",
+					"project": "billing",
+					"expires": "2026-12-31",
+				},
+			},
+			result: Result{true, ""},
+		},
+		{
+			name: "Missing required label",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":    "infra",
+					"expires": "2026-12-31",
+				},
+			},
+			result: Result{answer: false, msg: "labels.project"},
+		},
+		{
+			name: "No labels at all",
+			body: map[string]interface{}{
+				"Image": "alpine",
+			},
+			result: Result{answer: false, msg: "labels.team"},
+		},
+		{
+			name: "Spoofed compose label",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":                       "infra",
+					"project":                    "billing",
+					"expires":                    "2026-12-31",
+					"com.docker.compose.project": "billing",
+				},
+			},
+			result: Result{answer: false, msg: "labels.com.docker.compose.project"},
+		},
+		{
+			name: "Too many labels",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":    "infra",
+					"project": "billing",
+					"expires": "2026-12-31",
+					"owner":   "roman",
+					"env":     "dev",
+					"tier":    "backend",
+				},
+			},
+			result: Result{answer: false, msg: "labels"},
+		},
+		{
+			name: "Team is not in the enum",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":    "marketing",
+					"project": "billing",
+					"expires": "2026-12-31",
+				},
+			},
+			result: Result{answer: false, msg: "labels.team"},
+		},
+		{
+			name: "Expires doesn't match the regexp",
+			body: map[string]interface{}{
+				"Image": "alpine",
+				"Labels": map[string]string{
+					"team":    "data",
+					"project": "billing",
+					"expires": "never",
+				},
+			},
+			result: Result{answer: false, msg: "labels.expires"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheLabelPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/docker/go-units"
+)
+
+const (
+	logDriverType = "logdriver"
+	logOptionType = "logopt"
+)
+
+var (
+	// DefaultLogDriver is log-driver of daemon.json, docker daemon takes it for the container without --log-driver
+	DefaultLogDriver = "json-file"
+)
+
+// complyTheLogDriver checks HostConfig.LogConfig.Type is one of
+// valueFromPolitic: LogConfig,"[json-file,local,journald]",logdriver,AllowToUse.
+// Empty Type means the default driver of docker daemon and is allowed
+func complyTheLogDriver(body string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	driver := strings.ToLower(config.hostConfig().LogConfig.Type)
+	if driver == "" {
+		return true
+	}
+
+	for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+		if allowToUse == driver {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheLogOptions checks HostConfig.LogConfig.Config. The name of the rule is
+// LogConfig.<driver glob>.<option> or LogConfig[.<driver glob>] for ForbiddenKeys:
+// 1) MaxSize, if the option is absent or its size > valueFromPolitic - DENY
+// 2) Required, if the option is absent or isn't one of valueFromPolitic ("[]" for any value) - DENY
+// 3) AllowToUse, if the option is present and isn't one of valueFromPolitic - DENY
+// 4) MatchRegexp, if the option is present and doesn't match the regexp - DENY
+// 5) ForbiddenKeys, if some of options is one of valueFromPolitic - DENY.
+// Empty Type is DefaultLogDriver, docker daemon fills its absent options from log-opts of daemon.json
+func complyTheLogOptions(body string, nameOfKey string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	logConfig := config.hostConfig().LogConfig
+	driver := strings.ToLower(logConfig.Type)
+	defaulted := driver == ""
+	if defaulted {
+		driver = strings.ToLower(DefaultLogDriver)
+	}
+	options := make(map[string]string)
+	for option, value := range logConfig.Config {
+		options[strings.ToLower(option)] = value
+	}
+
+	parts := strings.SplitN(strings.ToLower(nameOfKey), ".", 3)
+	driverPattern := "*"
+	if len(parts) > 1 {
+		driverPattern = parts[1]
+	}
+	if match, _ := path.Match(driverPattern, driver); !match {
+		return true
+	}
+
+	if kindOfPolicy == ForbiddenKeys {
+		for _, forbidden := range sliceFromPolicy(strings.ToLower(valueFromCSV)) {
+			if _, found := options[forbidden]; found {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(parts) < 3 {
+		log.Println("The option is missing at the log policy:", nameOfKey)
+		return false
+	}
+	value, found := options[parts[2]]
+
+	switch kindOfPolicy {
+	case MaxSize:
+		if !found {
+			return defaulted
+		}
+		size, err := units.RAMInBytes(value)
+		if err != nil {
+			return false
+		}
+		maxSize, err := units.RAMInBytes(valueFromCSV)
+		if err != nil {
+			log.Println("Wrong MaxSize at the log policy:", valueFromCSV)
+			return false
+		}
+		return size <= maxSize
+	case Required, AllowToUse:
+		if !found {
+			return kindOfPolicy == AllowToUse || defaulted
+		}
+		allowed := sliceFromPolicy(strings.ToLower(valueFromCSV))
+		if kindOfPolicy == Required && len(allowed) == 0 {
+			return true
+		}
+		for _, allowToUse := range allowed {
+			if allowToUse == strings.ToLower(value) {
+				return true
+			}
+		}
+		return false
+	case MatchRegexp:
+		if !found {
+			return true
+		}
+		re, err := regexp.Compile(valueFromCSV)
+		if err != nil {
+			log.Println("Wrong regexp at the log policy:", valueFromCSV)
+			return false
+		}
+		return re.MatchString(value)
+	}
+	log.Println("I don't know this log policy!")
+	return false
+}
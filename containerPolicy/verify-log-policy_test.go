@@ -0,0 +1,88 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func logBody(driver string, options map[string]string) map[string]interface{} {
+	return map[string]interface{}{
+		"Image": "alpine",
+		"HostConfig": map[string]interface{}{
+			"LogConfig": map[string]interface{}{"Type": driver, "Config": options},
+		},
+	}
+}
+
+func TestLogRules(t *testing.T) {
+	PathToThePolicy = "testdata/log_policy.csv"
+
+	testCases := []AdmitTestCase{
+		{
+			name:   "Default driver",
+			body:   logBody("", map[string]string{}),
+			result: Result{true, ""},
+		},
+		{
+			name:   "Default driver with too big max-size",
+			body:   logBody("", map[string]string{"max-size": "100g"}),
+			result: Result{answer: false, msg: "logconfig.json-file.max-size"},
+		},
+		{
+			name:   "Default driver with the wrong tag",
+			body:   logBody("", map[string]string{"tag": "{{.ImageName}}"}),
+			result: Result{answer: false, msg: "logconfig.*.tag"},
+		},
+		{
+			name:   "Json-file with max-size",
+			body:   logBody("json-file", map[string]string{"max-size": "10m", "max-file": "3"}),
+			result: Result{true, ""},
+		},
+		{
+			name:   "Hide the activity",
+			body:   logBody("none", map[string]string{}),
+			result: Result{answer: false, msg: "logconfig"},
+		},
+		{
+			name:   "Json-file without max-size",
+			body:   logBody("json-file", map[string]string{}),
+			result: Result{answer: false, msg: "logconfig.json-file.max-size"},
+		},
+		{
+			name:   "Json-file with too big max-size",
+			body:   logBody("json-file", map[string]string{"max-size": "10g"}),
+			result: Result{answer: false, msg: "logconfig.json-file.max-size"},
+		},
+		{
+			name:   "Syslog to arbitrary host",
+			body:   logBody("syslog", map[string]string{"syslog-address": "udp://evil.example.com:514"}),
+			result: Result{answer: false, msg: "logconfig.syslog"},
+		},
+		{
+			name:   "Syslog of the daemon",
+			body:   logBody("syslog", map[string]string{"tag": "billing-api"}),
+			result: Result{true, ""},
+		},
+		{
+			name:   "Wrong tag",
+			body:   logBody("local", map[string]string{"tag": "{{.ImageName}}"}),
+			result: Result{answer: false, msg: "logconfig.*.tag"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheContainerPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
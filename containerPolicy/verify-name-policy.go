@@ -0,0 +1,68 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+)
+
+const (
+	MatchPattern = "MatchPattern"
+	AllowUnnamed = "AllowUnnamed"
+	nameType     = "name"
+)
+
+// Naming policy for creation and renaming container. Rows with type "name":
+// 1) MatchPattern, if name doesn't match any of patterns from politic - DENY.
+// Patterns can use the caller: "[${user}-*,${team}-*]"
+// 2) AllowUnnamed, if valueFromPolitic is false and name is empty - DENY
+func ComplyTheNamingPolicy(name string, caller identity.Identity) (bool, string) {
+	// there is no body at rename, so only the caller can scope the naming rules
+	rules, err := LoadRules(PathToThePolicy, Scope{Caller: caller})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// docker inspect shows names as /<name>
+	name = strings.TrimPrefix(name, "/")
+	for _, rule := range rules {
+		valueFromCSV := rule.Value
+		typeOfData := rule.Type
+		kindOfPolicy := rule.Kind
+
+		if typeOfData != nameType {
+			continue
+		}
+
+		switch kindOfPolicy {
+		case AllowUnnamed:
+			if name == "" && strings.ToLower(valueFromCSV) == "false" {
+				return false, nameType
+			}
+		case MatchPattern:
+			if name == "" {
+				continue
+			}
+			isItNameOK := false
+			for _, pattern := range sliceFromPolicy(valueFromCSV) {
+				pattern, ok := caller.Expand(pattern)
+				if !ok {
+					// we can't build the pattern for unknown caller
+					continue
+				}
+				if match, _ := path.Match(pattern, name); match {
+					isItNameOK = true
+					break
+				}
+			}
+			if !isItNameOK {
+				return false, nameType
+			}
+		default:
+			log.Println("I don't know this naming policy!")
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,61 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+type NameTestCase struct {
+	name          string
+	containerName string
+	caller        identity.Identity
+	result        Result
+}
+
+func TestComplyTheNamingPolicy(t *testing.T) {
+	PathToThePolicy = "testdata/name_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	stranger := identity.Identity{KeyHash: "e51cc637"}
+
+	testCases := []NameTestCase{
+		{
+			name:          "Name starts with the user",
+			containerName: "roman-web",
+			caller:        roman,
+			result:        Result{true, ""},
+		},
+		{
+			name:          "Name starts with the team",
+			containerName: "/infra-db",
+			caller:        roman,
+			result:        Result{true, ""},
+		},
+		{
+			name:          "Name of other user",
+			containerName: "anna-web",
+			caller:        roman,
+			result:        Result{answer: false, msg: "name"},
+		},
+		{
+			name:          "Unnamed container",
+			containerName: "",
+			caller:        roman,
+			result:        Result{answer: false, msg: "name"},
+		},
+		{
+			name:          "Unknown caller",
+			containerName: "-web",
+			caller:        stranger,
+			result:        Result{answer: false, msg: "name"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyTheNamingPolicy(testCase.containerName, testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	networkOptType = "networkopt"
+	subnetType     = "subnet"
+	flagType       = "flag"
+)
+
+var (
+	PathToTheNetworkPolicy = "containerPolicy/network_policy.csv"
+	// these networks belong to docker daemon, NetworkMode container:<id> is the network of the container
+	predefinedNetworks = []string{"", "default", "bridge", "host", "none"}
+)
+
+// flagOfNetwork returns the flag of the network the rule is written for
+func flagOfNetwork(request types.NetworkCreateRequest, nameOfKey string) (bool, bool) {
+	switch nameOfKey {
+	case "internal":
+		return request.Internal, true
+	case "attachable":
+		return request.Attachable, true
+	case "ingress":
+		return request.Ingress, true
+	case "enableipv6":
+		return request.EnableIPv6, true
+	}
+	return false, false
+}
+
+// isItInsideOfSubnet checks the subnet is one of allowed subnets or inside of it
+func isItInsideOfSubnet(subnet string, allowed []string) bool {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	ones, _ := network.Mask.Size()
+	for _, allowToUse := range allowed {
+		_, allowedNetwork, err := net.ParseCIDR(allowToUse)
+		if err != nil {
+			log.Println("Wrong subnet at the network policy:", allowToUse)
+			continue
+		}
+		allowedOnes, _ := allowedNetwork.Mask.Size()
+		if allowedNetwork.Contains(network.IP) && allowedOnes <= ones {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheSubnets checks every Subnet and IPRange of IPAM.Config is inside of the subnets from politic:
+// IPAM.Config.Subnet,"[10.200.0.0/16,172.30.0.0/16]",subnet,AllowToUse.
+// Without IPAM docker takes the subnet from its default pools
+func complyTheSubnets(request types.NetworkCreateRequest, kindOfPolicy string, valueFromCSV string) bool {
+	if kindOfPolicy != AllowToUse {
+		log.Println("I don't know this subnet policy!")
+		return false
+	}
+	if request.IPAM == nil {
+		return true
+	}
+	allowed := sliceFromPolicy(valueFromCSV)
+	for _, config := range request.IPAM.Config {
+		for _, subnet := range []string{config.Subnet, config.IPRange} {
+			if subnet != "" && !isItInsideOfSubnet(subnet, allowed) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Policy for the body of /networks/create. The rules live at network_policy.csv:
+// 1) Driver with type "driver" - AllowToUse, DoesntExpectToSee
+// 2) Options.<name> with type "networkopt" - AllowToUse, DoesntExpectToSee, see complyTheDriverOpt.
+// Options.parent,"[eth0,bond0*]",networkopt,DoesntExpectToSee forbids macvlan and ipvlan on these interfaces
+// 3) IPAM.Config.Subnet with type "subnet" - AllowToUse
+// 4) Internal, Attachable, Ingress, EnableIPv6 with type "flag" - ExpectToSee, the flag must be equal to valueFromPolitic.
+// The network with ConfigFrom takes the options of the config-only network, see ComplyTheNetworkConfigFrom
+func ComplyTheNetworkPolicy(body string, caller identity.Identity) (bool, string) {
+	var request types.NetworkCreateRequest
+	if err := decodeBody(body, &request); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+	return complyTheNetworkRequest(request, ScopeFromBody(body, caller), caller)
+}
+
+// ComplyTheNetworkConfigFrom checks the network with ConfigFrom: docker daemon gives it the options, IPAM and IPv6
+// of the config-only network, so the rules judge them together with the driver of the body
+func ComplyTheNetworkConfigFrom(body string, config types.NetworkResource, caller identity.Identity) (bool, string) {
+	var request types.NetworkCreateRequest
+	if err := decodeBody(body, &request); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+	request.Options = config.Options
+	request.IPAM = &config.IPAM
+	request.EnableIPv6 = config.EnableIPv6
+	return complyTheNetworkRequest(request, ScopeFromBody(body, caller), caller)
+}
+
+func complyTheNetworkRequest(request types.NetworkCreateRequest, scope Scope, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheNetworkPolicy, scope)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	driver := request.Driver
+	if driver == "" {
+		driver = "bridge"
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case driverType:
+			yes = complyTheImageSource(strings.ToLower(driver), rule.Kind, valueFromCSV)
+		case networkOptType:
+			yes = complyTheDriverOpt(request.Options, strings.TrimPrefix(nameOfKey, "options."), rule.Kind, valueFromCSV)
+		case subnetType:
+			yes = complyTheSubnets(request, rule.Kind, valueFromCSV)
+		case flagType:
+			flag, found := flagOfNetwork(request, nameOfKey)
+			expected, err := strconv.ParseBool(valueFromCSV)
+			yes = found && err == nil && rule.Kind == ExpectToSee && flag == expected
+		default:
+			log.Println("I don't know this network policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
+
+// NetworksOfContainer returns the user-defined networks the container is connected to at the create:
+// HostConfig.NetworkMode and the keys of NetworkingConfig.EndpointsConfig
+func NetworksOfContainer(body string) []string {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return nil
+	}
+
+	var networks []string
+	candidates := []string{string(config.hostConfig().NetworkMode)}
+	if config.NetworkingConfig != nil {
+		for name := range config.NetworkingConfig.EndpointsConfig {
+			candidates = append(candidates, name)
+		}
+	}
+	for _, name := range candidates {
+		if strings.HasPrefix(name, "container:") {
+			continue
+		}
+		isItPredefined := false
+		for _, predefined := range predefinedNetworks {
+			if name == predefined {
+				isItPredefined = true
+			}
+		}
+		if !isItPredefined {
+			networks = append(networks, name)
+		}
+	}
+	return networks
+}
+
+// ContainersOfNamespaces returns the containers whose namespaces the new container joins:
+// --network container:<name|id>, --pid container:<name|id> and --ipc container:<name|id>
+func ContainersOfNamespaces(body string) []string {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return nil
+	}
+
+	var containers []string
+	hostConfig := config.hostConfig()
+	for _, mode := range []string{string(hostConfig.NetworkMode), string(hostConfig.PidMode), string(hostConfig.IpcMode)} {
+		if strings.HasPrefix(mode, "container:") {
+			containers = append(containers, strings.TrimPrefix(mode, "container:"))
+		}
+	}
+	return containers
+}
+
+// NetworkBody is what the plugin needs from the bodies of /networks: Name and ConfigFrom of the create,
+// Id of the created network from the response and Container of connect and disconnect
+type NetworkBody struct {
+	Name       string
+	Id         string
+	Container  string
+	ConfigFrom struct {
+		Network string
+	}
+}
+
+// DecodeNetworkBody returns the empty NetworkBody if the body can't be decoded
+func DecodeNetworkBody(body string) NetworkBody {
+	var networkBody NetworkBody
+	_ = json.Unmarshal([]byte(body), &networkBody)
+	return networkBody
+}
@@ -0,0 +1,126 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheNetworkPolicy(t *testing.T) {
+	PathToTheNetworkPolicy = "testdata/network_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []AdmitTestCase{
+		{
+			name:   "Bridge network",
+			body:   map[string]interface{}{"Name": "roman-net"},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Network of other driver",
+			body:   map[string]interface{}{"Name": "roman-net", "Driver": "ipvlan"},
+			caller: roman,
+			result: Result{answer: false, msg: "driver"},
+		},
+		{
+			name:   "Macvlan on the main interface",
+			body:   map[string]interface{}{"Name": "roman-net", "Driver": "macvlan", "Options": map[string]string{"parent": "eth0"}},
+			caller: roman,
+			result: Result{answer: false, msg: "options.parent"},
+		},
+		{
+			name:   "Macvlan on the vlan of the bond",
+			body:   map[string]interface{}{"Name": "roman-net", "Driver": "macvlan", "Options": map[string]string{"parent": "bond0.100"}},
+			caller: roman,
+			result: Result{answer: false, msg: "options.parent"},
+		},
+		{
+			name:   "Macvlan on the lab interface",
+			body:   map[string]interface{}{"Name": "roman-net", "Driver": "macvlan", "Options": map[string]string{"parent": "eth1"}},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Subnet inside of the pool",
+			body:   map[string]interface{}{"Name": "roman-net", "IPAM": map[string]interface{}{"Config": []map[string]string{{"Subnet": "10.200.12.0/24", "IPRange": "10.200.12.128/25"}}}},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Subnet of the office",
+			body:   map[string]interface{}{"Name": "roman-net", "IPAM": map[string]interface{}{"Config": []map[string]string{{"Subnet": "192.168.0.0/24"}}}},
+			caller: roman,
+			result: Result{answer: false, msg: "ipam.config.subnet"},
+		},
+		{
+			name:   "Subnet bigger than the pool",
+			body:   map[string]interface{}{"Name": "roman-net", "IPAM": map[string]interface{}{"Config": []map[string]string{{"Subnet": "10.0.0.0/8"}}}},
+			caller: roman,
+			result: Result{answer: false, msg: "ipam.config.subnet"},
+		},
+		{
+			name:   "Attachable network",
+			body:   map[string]interface{}{"Name": "roman-net", "Attachable": true},
+			caller: roman,
+			result: Result{answer: false, msg: "attachable"},
+		},
+		{
+			name:   "Internal network is not internal",
+			body:   map[string]interface{}{"Name": "roman-net", "Labels": map[string]string{"exposure": "internal"}},
+			caller: roman,
+			result: Result{answer: false, msg: "internal"},
+		},
+		{
+			name:   "Internal network",
+			body:   map[string]interface{}{"Name": "roman-net", "Internal": true, "Labels": map[string]string{"exposure": "internal"}},
+			caller: roman,
+			result: Result{true, ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheNetworkPolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestComplyTheNetworkConfigFrom(t *testing.T) {
+	PathToTheNetworkPolicy = "testdata/network_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	body := `{"Name":"roman-mac","Driver":"macvlan","ConfigFrom":{"Network":"roman-cfg"}}`
+
+	yes, msg := ComplyTheNetworkConfigFrom(body, types.NetworkResource{Options: map[string]string{"parent": "eth0"}}, roman)
+	assert.Equal(t, Result{answer: false, msg: "options.parent"}, Result{yes, msg})
+
+	office := network.IPAM{Config: []network.IPAMConfig{{Subnet: "192.168.0.0/24"}}}
+	yes, msg = ComplyTheNetworkConfigFrom(body, types.NetworkResource{Options: map[string]string{"parent": "eth1"}, IPAM: office}, roman)
+	assert.Equal(t, Result{answer: false, msg: "ipam.config.subnet"}, Result{yes, msg})
+
+	yes, msg = ComplyTheNetworkConfigFrom(body, types.NetworkResource{Options: map[string]string{"parent": "eth1"}}, roman)
+	assert.Equal(t, Result{true, ""}, Result{yes, msg})
+
+	yes, msg = ComplyTheNetworkConfigFrom(`{"Name":"roman-ip","Driver":"ipvlan","ConfigFrom":{"Network":"roman-cfg"}}`, types.NetworkResource{}, roman)
+	assert.Equal(t, Result{answer: false, msg: "driver"}, Result{yes, msg})
+}
+
+func TestNetworksOfContainer(t *testing.T) {
+	body := `{"Image":"alpine","HostConfig":{"NetworkMode":"anna-net"},"NetworkingConfig":{"EndpointsConfig":{"bridge":{}}}}`
+	assert.Equal(t, []string{"anna-net"}, NetworksOfContainer(body))
+
+	body = `{"Image":"alpine","HostConfig":{"NetworkMode":"container:db"}}`
+	assert.Empty(t, NetworksOfContainer(body))
+}
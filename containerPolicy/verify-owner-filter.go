@@ -0,0 +1,90 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types/filters"
+)
+
+const (
+	// OwnerLabel marks containers, images, volumes and networks of the caller: authz.owner=roman
+	OwnerLabel = "authz.owner"
+)
+
+// ownersOfCaller returns the values of OwnerLabel the caller can use:
+// the user from users.csv and the hash of AuthHeader
+func ownersOfCaller(caller identity.Identity) []string {
+	var owners []string
+	for _, owner := range []string{caller.User, caller.KeyHash} {
+		if owner != "" {
+			owners = append(owners, owner)
+		}
+	}
+	return owners
+}
+
+// isItCallersOwner checks the value of OwnerLabel is the user or the hash of the caller
+func isItCallersOwner(owner string, caller identity.Identity) bool {
+	for _, allowed := range ownersOfCaller(caller) {
+		if owner == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ComplyTheOwnerFilter checks the filters query of the prune or the list restricts it to the caller.
+// One of "label" filters must be authz.owner=<user or hash>, the filters are joined with AND by docker daemon,
+// so the other filters can only narrow it. Every authz.owner filter must be caller's:
+// 1) filters is absent or broken - DENY
+// 2) label=authz.owner without the value or label=authz.owner=anna - DENY
+func ComplyTheOwnerFilter(filtersJSON string, caller identity.Identity) bool {
+	if filtersJSON == "" {
+		return false
+	}
+	args, err := filters.FromJSON(filtersJSON)
+	if err != nil {
+		return false
+	}
+
+	isItRestricted := false
+	for _, label := range args.Get("label") {
+		parts := strings.SplitN(label, "=", 2)
+		if strings.TrimSpace(parts[0]) != OwnerLabel {
+			continue
+		}
+		if len(parts) != 2 || !isItCallersOwner(parts[1], caller) {
+			return false
+		}
+		isItRestricted = true
+	}
+	return isItRestricted
+}
+
+// OwnerFilterHint returns the flag of docker CLI the caller has to add: --filter label=authz.owner=roman.
+// The caller without the user at users.csv gets the hash of AuthHeader
+func OwnerFilterHint(caller identity.Identity) string {
+	owners := ownersOfCaller(caller)
+	if len(owners) == 0 {
+		return "--filter label=" + OwnerLabel + "=<your user>"
+	}
+	return "--filter label=" + OwnerLabel + "=" + owners[0]
+}
+
+// ComplyTheOwnerLabel checks nobody marks his resource as other's: if OwnerLabel is present,
+// it must be the user or the hash of the caller. Otherwise the prune of the owner removes it
+func ComplyTheOwnerLabel(labels map[string]string, caller identity.Identity) bool {
+	owner, found := labels[OwnerLabel]
+	return !found || isItCallersOwner(owner, caller)
+}
+
+// LabelsFromBody returns Labels of the create body of containers, volumes and networks
+func LabelsFromBody(body string) map[string]string {
+	var config struct {
+		Labels map[string]string
+	}
+	_ = json.Unmarshal([]byte(body), &config)
+	return config.Labels
+}
@@ -0,0 +1,50 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheOwnerFilter(t *testing.T) {
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []struct {
+		name    string
+		filters string
+		result  bool
+	}{
+		{"Without filters", "", false},
+		{"Broken filters", `{"label":`, false},
+		{"Filter of the time only", `{"until":{"24h":true}}`, false},
+		{"Filter of the user", `{"label":{"authz.owner=roman":true}}`, true},
+		{"Filter of the hash", `{"label":{"authz.owner=7c3aa42f":true},"until":{"24h":true}}`, true},
+		{"Legacy filter of the user", `{"label":["authz.owner=roman"]}`, true},
+		{"Filter of other's user", `{"label":{"authz.owner=anna":true}}`, false},
+		{"Filter of the user and other's user", `{"label":{"authz.owner=roman":true,"authz.owner=anna":true}}`, false},
+		{"Filter of any owner", `{"label":{"authz.owner":true}}`, false},
+		{"Negative filter of other's user", `{"label!":{"authz.owner=anna":true}}`, false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, ComplyTheOwnerFilter(testCase.filters, roman))
+		})
+	}
+}
+
+func TestComplyTheOwnerLabel(t *testing.T) {
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	assert.True(t, ComplyTheOwnerLabel(nil, roman))
+	assert.True(t, ComplyTheOwnerLabel(map[string]string{"authz.owner": "roman"}, roman))
+	assert.False(t, ComplyTheOwnerLabel(map[string]string{"authz.owner": "anna"}, roman))
+	assert.False(t, ComplyTheOwnerLabel(map[string]string{"authz.owner": "anna"}, identity.Identity{KeyHash: "e51cc637"}))
+}
+
+func TestOwnerFilterHint(t *testing.T) {
+	assert.Equal(t, "--filter label=authz.owner=roman", OwnerFilterHint(identity.Identity{KeyHash: "7c3aa42f", User: "roman"}))
+	assert.Equal(t, "--filter label=authz.owner=e51cc637", OwnerFilterHint(identity.Identity{KeyHash: "e51cc637"}))
+	assert.Equal(t, "--filter label=authz.owner=<your user>", OwnerFilterHint(identity.Identity{}))
+}
@@ -0,0 +1,99 @@
+package containerpolicy
+
+import (
+	"log"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types"
+)
+
+const (
+	pluginType    = "plugin"
+	privilegeType = "privilege"
+)
+
+var (
+	PathToThePluginPolicy = "containerPolicy/plugin_policy.csv"
+)
+
+// PluginName returns the name of the plugin without the registry, the namespace and the tag:
+// registry.corp.internal/corp/container-authz-plugin:1.0 is container-authz-plugin
+func PluginName(plugin string) string {
+	named, err := reference.ParseNormalizedNamed(plugin)
+	if err != nil {
+		return strings.ToLower(plugin)
+	}
+	return path.Base(reference.FamiliarName(named))
+}
+
+// complyThePrivileges checks every value of the privileges with the name of the rule.
+// docker plugin install shows them to the user, the body of /plugins/pull sends them back:
+// network (host), mount (/var/lib/docker), device (/dev/fuse), allow-all-devices (true),
+// capabilities (CAP_SYS_ADMIN), host pid (true)
+func complyThePrivileges(privileges []types.PluginPrivilege, nameOfKey string, kindOfPolicy string, valueFromCSV string) bool {
+	for _, privilege := range privileges {
+		if strings.ToLower(privilege.Name) != nameOfKey {
+			continue
+		}
+		for _, value := range privilege.Value {
+			value = strings.ToLower(value)
+			if strings.HasPrefix(value, "/") {
+				value = path.Clean(value)
+			}
+			if !complyTheImageSource(value, kindOfPolicy, valueFromCSV) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Policy for /plugins/pull and /plugins/{name}/upgrade. The rules live at plugin_policy.csv:
+// 1) Remote with type "plugin" - AllowToUse, DoesntExpectToSee for the whole reference, "*" matches "/" too:
+// Remote,"[docker.io/vieux/sshfs:*,registry.corp.internal/plugins/*]",plugin,AllowToUse
+// 2) <name of the privilege> with type "privilege" - AllowToUse, DoesntExpectToSee for its values:
+// capabilities,"[cap_sys_admin]",privilege,DoesntExpectToSee. The privilege without the rule is allowed.
+// The rules with the selector "image" are for the reference of the plugin
+func ComplyThePluginPolicy(remote string, body string, caller identity.Identity) (bool, string) {
+	remote = strings.ToLower(NormalizeImage(remote))
+	rules, err := LoadRules(PathToThePluginPolicy, Scope{Caller: caller, Image: remote})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	// docker CLI always sends the privileges, without them docker daemon refuses the pull
+	var privileges []types.PluginPrivilege
+	if strings.TrimSpace(body) != "" {
+		if err := decodeBody(body, &privileges); err != nil {
+			return false, "Error decoding the body: " + err.Error()
+		}
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case pluginType:
+			yes = complyTheImageSource(remote, rule.Kind, valueFromCSV)
+		case privilegeType:
+			yes = complyThePrivileges(privileges, nameOfKey, rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this plugin policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
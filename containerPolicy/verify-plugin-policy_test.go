@@ -0,0 +1,78 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyThePluginPolicy(t *testing.T) {
+	PathToThePluginPolicy = "testdata/plugin_policy.csv"
+	admin := identity.Identity{KeyHash: "f3ab0df5"}
+
+	testCases := []struct {
+		name   string
+		remote string
+		body   string
+		result Result
+	}{
+		{
+			name:   "Sshfs with its privileges",
+			remote: "vieux/sshfs",
+			body: `[{"Name":"network","Value":["host"]},{"Name":"mount","Value":["/var/lib/docker/plugins/"]},` +
+				`{"Name":"device","Value":["/dev/fuse"]},{"Name":"capabilities","Value":["CAP_SYS_ADMIN"]}]`,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Plugin from docker hub",
+			remote: "store/weaveworks/net-plugin:2.5.2",
+			body:   `[]`,
+			result: Result{answer: false, msg: "remote"},
+		},
+		{
+			name:   "Plugin mounts the root of the host",
+			remote: "vieux/sshfs:next",
+			body:   `[{"Name":"mount","Value":["/var/lib/docker/plugins/../../../"]}]`,
+			result: Result{answer: false, msg: "mount"},
+		},
+		{
+			name:   "Plugin wants all capabilities",
+			remote: "vieux/sshfs",
+			body:   `[{"Name":"capabilities","Value":["CAP_SYS_ADMIN","CAP_SYS_MODULE"]}]`,
+			result: Result{answer: false, msg: "capabilities"},
+		},
+		{
+			name:   "Plugin wants all devices",
+			remote: "registry.corp.internal/plugins/storage:1.0",
+			body:   `[{"Name":"allow-all-devices","Value":["true"]}]`,
+			result: Result{answer: false, msg: "allow-all-devices"},
+		},
+		{
+			name:   "Corporate plugin on the network of the host",
+			remote: "registry.corp.internal/plugins/storage:1.0",
+			body:   `[{"Name":"network","Value":["host"]}]`,
+			result: Result{answer: false, msg: "network"},
+		},
+		{
+			name:   "Broken privileges",
+			remote: "vieux/sshfs",
+			body:   `{"Name":"network"}`,
+			result: Result{answer: false, msg: "Error decoding the body: json: cannot unmarshal object into Go value of type []types.PluginPrivilege"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, msg := ComplyThePluginPolicy(testCase.remote, testCase.body, admin)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	assert.Equal(t, "container-authz-plugin", PluginName("container-authz-plugin"))
+	assert.Equal(t, "container-authz-plugin", PluginName("container-authz-plugin:latest"))
+	assert.Equal(t, "container-authz-plugin", PluginName("registry.corp.internal/corp/container-authz-plugin:1.0"))
+	assert.Equal(t, "sshfs", PluginName("vieux/sshfs"))
+}
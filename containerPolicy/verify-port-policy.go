@@ -0,0 +1,55 @@
+package containerpolicy
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	portRangeType = "portrange"
+)
+
+// parsePortRange turns "8000-8005" or "8000" into the first and the last port
+func parsePortRange(portRange string) (int, int, bool) {
+	parts := strings.SplitN(portRange, "-", 2)
+	first, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	last := first
+	if len(parts) == 2 {
+		last, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+	return first, last, true
+}
+
+// complyThePortRange checks every HostPort of HostConfig.PortBindings
+// is inside of the range from politic: PortBindings,"${uid}0-${uid}9",portrange,AllowToUse.
+// Empty HostPort is allowed, docker will pick an ephemeral port
+func complyThePortRange(body string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+
+	allowedFirst, allowedLast, ok := parsePortRange(valueFromCSV)
+	if !ok {
+		return false
+	}
+
+	for _, bindings := range config.hostConfig().PortBindings {
+		for _, binding := range bindings {
+			if binding.HostPort == "" {
+				continue
+			}
+			first, last, ok := parsePortRange(binding.HostPort)
+			if !ok || first < allowedFirst || last > allowedLast {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package containerpolicy
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/mount"
+)
+
+const (
+	NonRoot       = "NonRoot"
+	userType      = "user"
+	hostMountType = "hostmount"
+)
+
+// complyTheUser checks Config.User, see complyTheUserName
+func complyTheUser(body string, kindOfPolicy string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	return complyTheUserName(config.User, kindOfPolicy, valueFromCSV)
+}
+
+// userOfUserAndGroup returns the user of user[:group] the way the runtime reads it:
+// the uid is the number, so "00" and "+0" are "0", the name is lowercased
+func userOfUserAndGroup(userAndGroup string) string {
+	user := strings.TrimSpace(strings.SplitN(userAndGroup, ":", 2)[0])
+	if uid, err := strconv.Atoi(user); err == nil {
+		return strconv.Itoa(uid)
+	}
+	return strings.ToLower(user)
+}
+
+// complyTheUserName checks user[:group]. NonRoot - the user must be set,
+// because the empty one means the user of the image, and must not be root or 0.
+// DoesntExpectToSee - the user must not be one of valueFromPolitic.
+// AllowToUse - the user must be one of valueFromPolitic.
+// The uids of the body and of the policy are compared as the numbers
+func complyTheUserName(userAndGroup string, kindOfPolicy string, valueFromCSV string) bool {
+	// user:group, we judge only the user
+	user := userOfUserAndGroup(userAndGroup)
+
+	switch kindOfPolicy {
+	case NonRoot:
+		return user != "" && user != "root" && user != "0"
+	case DoesntExpectToSee:
+		for _, dontExpect := range sliceFromPolicy(valueFromCSV) {
+			if userOfUserAndGroup(dontExpect) == user {
+				return false
+			}
+		}
+		return true
+	case AllowToUse:
+		for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+			if userOfUserAndGroup(allowToUse) == user {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// isItAllowedHostPath checks the source is one of allowed host paths or inside of it.
+// The source is cleaned first, /home/roman/../../etc is /etc
+func isItAllowedHostPath(source string, allowed []string) bool {
+	source = path.Clean(source)
+	for _, allowToUse := range allowed {
+		allowToUse = path.Clean(allowToUse)
+		if source == allowToUse || strings.HasPrefix(source, strings.TrimSuffix(allowToUse, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// complyTheHostMounts checks the sources of the host mounts from HostConfig.Binds
+// and HostConfig.Mounts are inside of allowed host paths. Named volumes are not
+// host mounts, so they are not checked here. "[]" forbids all host mounts
+func complyTheHostMounts(body string, valueFromCSV string) bool {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false
+	}
+	allowed := sliceFromPolicy(valueFromCSV)
+
+	for _, bind := range config.hostConfig().Binds {
+		source := strings.SplitN(bind, ":", 2)[0]
+		if !strings.HasPrefix(source, "/") {
+			continue
+		}
+		if !isItAllowedHostPath(strings.ToLower(source), allowed) {
+			return false
+		}
+	}
+	for _, m := range config.hostConfig().Mounts {
+		if m.Type != mount.TypeBind {
+			continue
+		}
+		if !isItAllowedHostPath(strings.ToLower(m.Source), allowed) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+)
+
+// decodeServiceSpec decodes the body of /services/create and /services/{id}/update
+func decodeServiceSpec(body string) (swarm.ServiceSpec, error) {
+	var spec swarm.ServiceSpec
+	err := decodeBody(body, &spec)
+	return spec, err
+}
+
+// extraHostsOfService turns "10.0.0.1 db db.local" of swarmkit into "db:10.0.0.1" and "db.local:10.0.0.1" of docker
+func extraHostsOfService(hosts []string) []string {
+	var extraHosts []string
+	for _, host := range hosts {
+		fields := strings.Fields(host)
+		if len(fields) < 2 {
+			extraHosts = append(extraHosts, host)
+			continue
+		}
+		for _, name := range fields[1:] {
+			extraHosts = append(extraHosts, name+":"+fields[0])
+		}
+	}
+	return extraHosts
+}
+
+// securityOptOfService turns Privileges.SELinuxContext into SecurityOpt of docker run
+func securityOptOfService(privileges *swarm.Privileges) []string {
+	if privileges == nil || privileges.SELinuxContext == nil {
+		return nil
+	}
+	context := privileges.SELinuxContext
+	if context.Disable {
+		return []string{"label=disable"}
+	}
+	var securityOpt []string
+	options := []struct{ name, value string }{
+		{"user", context.User}, {"role", context.Role}, {"type", context.Type}, {"level", context.Level},
+	}
+	for _, option := range options {
+		if option.value != "" {
+			securityOpt = append(securityOpt, "label="+option.name+":"+option.value)
+		}
+	}
+	return securityOpt
+}
+
+// ContainerBodyFromService turns TaskTemplate.ContainerSpec into the body of /containers/create,
+// so the container policy judges the tasks of the service like the containers.
+// The service without ContainerSpec runs a plugin, we can't judge it
+func ContainerBodyFromService(body string) (string, error) {
+	spec, err := decodeServiceSpec(body)
+	if err != nil {
+		return "", err
+	}
+	task := spec.TaskTemplate
+	containerSpec := task.ContainerSpec
+	if containerSpec == nil {
+		return "", errors.New("the service has no ContainerSpec")
+	}
+
+	config := container.Config{
+		Image:       containerSpec.Image,
+		Labels:      containerSpec.Labels,
+		Entrypoint:  containerSpec.Command,
+		Cmd:         containerSpec.Args,
+		Hostname:    containerSpec.Hostname,
+		Env:         containerSpec.Env,
+		WorkingDir:  containerSpec.Dir,
+		User:        containerSpec.User,
+		StopSignal:  containerSpec.StopSignal,
+		Tty:         containerSpec.TTY,
+		OpenStdin:   containerSpec.OpenStdin,
+		Healthcheck: containerSpec.Healthcheck,
+	}
+	hostConfig := container.HostConfig{
+		GroupAdd:       containerSpec.Groups,
+		Init:           containerSpec.Init,
+		ReadonlyRootfs: containerSpec.ReadOnly,
+		Mounts:         containerSpec.Mounts,
+		ExtraHosts:     extraHostsOfService(containerSpec.Hosts),
+		Isolation:      containerSpec.Isolation,
+		Sysctls:        containerSpec.Sysctls,
+		CapAdd:         containerSpec.CapabilityAdd,
+		CapDrop:        containerSpec.CapabilityDrop,
+		SecurityOpt:    securityOptOfService(containerSpec.Privileges),
+	}
+	if containerSpec.DNSConfig != nil {
+		hostConfig.DNS = containerSpec.DNSConfig.Nameservers
+		hostConfig.DNSSearch = containerSpec.DNSConfig.Search
+		hostConfig.DNSOptions = containerSpec.DNSConfig.Options
+	}
+	hostConfig.Ulimits = containerSpec.Ulimits
+	if task.Resources != nil && task.Resources.Limits != nil {
+		limits := task.Resources.Limits
+		hostConfig.NanoCPUs = limits.NanoCPUs
+		hostConfig.Memory = limits.MemoryBytes
+		if limits.Pids != 0 {
+			hostConfig.PidsLimit = &limits.Pids
+		}
+	}
+	if task.LogDriver != nil {
+		hostConfig.LogConfig = container.LogConfig{Type: task.LogDriver.Name, Config: task.LogDriver.Options}
+	}
+
+	// Networks of the service are deprecated, but docker daemon still takes them
+	networkingConfig := &network.NetworkingConfig{EndpointsConfig: make(map[string]*network.EndpointSettings)}
+	for _, attachment := range append(task.Networks, spec.Networks...) {
+		// --network host gives the tasks the network of the host
+		if attachment.Target == "host" {
+			hostConfig.NetworkMode = "host"
+			continue
+		}
+		networkingConfig.EndpointsConfig[attachment.Target] = &network.EndpointSettings{Aliases: attachment.Aliases}
+	}
+
+	containerBody, err := json.Marshal(struct {
+		container.Config
+		HostConfig       *container.HostConfig
+		NetworkingConfig *network.NetworkingConfig
+	}{config, &hostConfig, networkingConfig})
+	return string(containerBody), err
+}
+
+// SecretsAndConfigsOfService returns the secrets and the configs the tasks of the service get.
+// Docker daemon takes SecretID, SecretName is only for the humans
+func SecretsAndConfigsOfService(body string) ([]string, []string) {
+	spec, err := decodeServiceSpec(body)
+	if err != nil || spec.TaskTemplate.ContainerSpec == nil {
+		return nil, nil
+	}
+
+	var secrets, configs []string
+	for _, secret := range spec.TaskTemplate.ContainerSpec.Secrets {
+		if secret == nil {
+			continue
+		}
+		if secret.SecretID != "" {
+			secrets = append(secrets, secret.SecretID)
+		} else {
+			secrets = append(secrets, secret.SecretName)
+		}
+	}
+	for _, config := range spec.TaskTemplate.ContainerSpec.Configs {
+		if config == nil {
+			continue
+		}
+		if config.ConfigID != "" {
+			configs = append(configs, config.ConfigID)
+		} else {
+			configs = append(configs, config.ConfigName)
+		}
+	}
+	return secrets, configs
+}
+
+// SwarmObject is Name of the create body of the secret or the config and ID of the response
+type SwarmObject struct {
+	Name string
+	ID   string
+}
+
+// DecodeSwarmObject returns the empty SwarmObject if the body can't be decoded
+func DecodeSwarmObject(body string) SwarmObject {
+	var object SwarmObject
+	_ = json.Unmarshal([]byte(body), &object)
+	return object
+}
@@ -0,0 +1,82 @@
+package containerpolicy
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerBodyFromService(t *testing.T) {
+	body := `{"Name":"web","TaskTemplate":{"ContainerSpec":{"Image":"nginx:1.25","Command":["nsenter"],"Args":["-t","1"],` +
+		`"Hosts":["10.0.0.1 db db.local"],"CapabilityAdd":["SYS_ADMIN"],"ReadOnly":true,` +
+		`"Mounts":[{"Type":"bind","Source":"/","Target":"/host"}],"Privileges":{"SELinuxContext":{"Disable":true}}},` +
+		`"Resources":{"Limits":{"MemoryBytes":104857600,"Pids":100}},"Networks":[{"Target":"anna-net"},{"Target":"host"}]}}`
+
+	containerBody, err := ContainerBodyFromService(body)
+	assert.NoError(t, err)
+	config, err := decodeContainerBody(containerBody)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "nginx:1.25", config.Image)
+	assert.Equal(t, []string{"nsenter"}, []string(config.Entrypoint))
+	assert.Equal(t, []string{"-t", "1"}, []string(config.Cmd))
+	assert.Equal(t, []string{"db:10.0.0.1", "db.local:10.0.0.1"}, config.hostConfig().ExtraHosts)
+	assert.Equal(t, []string{"SYS_ADMIN"}, []string(config.hostConfig().CapAdd))
+	assert.Equal(t, []string{"label=disable"}, config.hostConfig().SecurityOpt)
+	assert.Equal(t, "/", config.hostConfig().Mounts[0].Source)
+	assert.True(t, config.hostConfig().ReadonlyRootfs)
+	assert.Equal(t, int64(104857600), config.hostConfig().Memory)
+	assert.Equal(t, int64(100), *config.hostConfig().PidsLimit)
+	assert.Equal(t, "host", string(config.hostConfig().NetworkMode))
+	assert.Equal(t, []string{"anna-net"}, NetworksOfContainer(containerBody))
+
+	_, err = ContainerBodyFromService(`{"Name":"plugin","TaskTemplate":{"Runtime":"plugin"}}`)
+	assert.Error(t, err)
+}
+
+func TestServiceUnderTheContainerPolicy(t *testing.T) {
+	PathToThePolicy = "testdata/command_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	testCases := []struct {
+		name   string
+		body   string
+		result Result
+	}{
+		{
+			name:   "Good service",
+			body:   `{"TaskTemplate":{"ContainerSpec":{"Image":"alpine","Args":["sleep","infinity"]}}}`,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Nsenter at the command of the service",
+			body:   `{"TaskTemplate":{"ContainerSpec":{"Image":"alpine","Command":["nsenter"],"Args":["-t","1","-m","sh"]}}}`,
+			result: Result{answer: false, msg: "command"},
+		},
+		{
+			name:   "Service without tini",
+			body:   `{"TaskTemplate":{"ContainerSpec":{"Image":"alpine","Labels":{"service":"web"},"Args":["nginx"]}}}`,
+			result: Result{answer: false, msg: "entrypoint"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			containerBody, err := ContainerBodyFromService(testCase.body)
+			assert.NoError(t, err)
+			response, msg := ComplyTheContainerPolicy(containerBody, roman)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestSecretsAndConfigsOfService(t *testing.T) {
+	body := `{"TaskTemplate":{"ContainerSpec":{"Image":"alpine",` +
+		`"Secrets":[{"SecretID":"ktnbjxoalbkvbvedmg1urrz8h","SecretName":"roman-token"},{"SecretName":"anna-token"}],` +
+		`"Configs":[{"ConfigID":"yf9kq6i0eakvq3xwp5y3r2ts1","ConfigName":"nginx.conf"}]}}}`
+
+	secrets, configs := SecretsAndConfigsOfService(body)
+	assert.Equal(t, []string{"ktnbjxoalbkvbvedmg1urrz8h", "anna-token"}, secrets)
+	assert.Equal(t, []string{"yf9kq6i0eakvq3xwp5y3r2ts1"}, configs)
+}
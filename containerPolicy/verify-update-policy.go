@@ -0,0 +1,206 @@
+package containerpolicy
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+const (
+	MaxFactor         = "MaxFactor"
+	restartPolicyType = "restartpolicy"
+	unlimited         = math.MaxInt64
+)
+
+var (
+	PathToTheUpdatePolicy = "containerPolicy/update_policy.csv"
+)
+
+// limitOfResources returns the limit the rule is written for and false if it isn't set.
+// Update body has 0 for the limits which are not changed, unlimited is math.MaxInt64
+func limitOfResources(resources container.Resources, nameOfKey string) (int64, bool, bool) {
+	var limit int64
+	switch nameOfKey {
+	case "memory":
+		limit = resources.Memory
+	case "memoryswap":
+		limit = resources.MemorySwap
+	case "memoryreservation":
+		limit = resources.MemoryReservation
+	case "nanocpus":
+		limit = resources.NanoCPUs
+	case "cpushares":
+		limit = resources.CPUShares
+	case "cpuquota":
+		limit = resources.CPUQuota
+	case "pidslimit":
+		if resources.PidsLimit == nil {
+			return 0, false, true
+		}
+		// 0 and -1 are unlimited for pids
+		if *resources.PidsLimit <= 0 {
+			return unlimited, true, true
+		}
+		return *resources.PidsLimit, true, true
+	default:
+		return 0, false, false
+	}
+
+	if limit == 0 {
+		return 0, false, true
+	}
+	if limit < 0 {
+		return unlimited, true, true
+	}
+	return limit, true, true
+}
+
+// complyTheLimit checks the new limit:
+// 1) MaxValue, if the new limit > valueFromPolitic - DENY
+// 2) MaxFactor, if the new limit > the current limit * valueFromPolitic - DENY
+func complyTheLimit(update container.UpdateConfig, current *container.HostConfig, nameOfKey string, typeOfData string, kindOfPolicy string, valueFromCSV string) bool {
+	newLimit, set, known := limitOfResources(update.Resources, nameOfKey)
+	if !known {
+		log.Println("I don't know this limit:", nameOfKey)
+		return false
+	}
+	if !set {
+		return true
+	}
+
+	switch kindOfPolicy {
+	case MaxValue:
+		var bound int64
+		var err error
+		if typeOfData == sizeType {
+			bound, err = units.RAMInBytes(valueFromCSV)
+		} else {
+			bound, err = strconv.ParseInt(valueFromCSV, 10, 64)
+		}
+		if err != nil {
+			log.Println("Wrong MaxValue at the update policy:", valueFromCSV)
+			return false
+		}
+		return newLimit <= bound
+	case MaxFactor:
+		factor, err := strconv.ParseFloat(valueFromCSV, 64)
+		if err != nil {
+			log.Println("Wrong MaxFactor at the update policy:", valueFromCSV)
+			return false
+		}
+		// we can't compare without the current limits
+		if current == nil {
+			return false
+		}
+		currentLimit, set, _ := limitOfResources(current.Resources, nameOfKey)
+		// any limit is not more than no limit at all
+		if !set || currentLimit == unlimited {
+			return true
+		}
+		return newLimit != unlimited && float64(newLimit) <= float64(currentLimit)*factor
+	}
+	log.Println("I don't know this limit policy!")
+	return false
+}
+
+// complyTheRestartPolicy checks RestartPolicy of update:
+// 1) RestartPolicy,"[no,on-failure]",restartpolicy,AllowToUse, if the name isn't one of valueFromPolitic - DENY
+// 2) RestartPolicy.MaximumRetryCount,5,restartpolicy,MaxValue, if the count > valueFromPolitic - DENY
+func complyTheRestartPolicy(restartPolicy container.RestartPolicy, kindOfPolicy string, valueFromCSV string) bool {
+	switch kindOfPolicy {
+	case AllowToUse:
+		if restartPolicy.Name == "" {
+			return true
+		}
+		for _, allowToUse := range sliceFromPolicy(valueFromCSV) {
+			if allowToUse == strings.ToLower(string(restartPolicy.Name)) {
+				return true
+			}
+		}
+		return false
+	case MaxValue:
+		maxValue, err := strconv.Atoi(valueFromCSV)
+		if err != nil {
+			log.Println("Wrong MaxValue at the update policy:", valueFromCSV)
+			return false
+		}
+		return restartPolicy.MaximumRetryCount <= maxValue
+	}
+	log.Println("I don't know this restart policy!")
+	return false
+}
+
+// Policy for POST /containers/{id}/update. The rules live at update_policy.csv,
+// the body is UpdateConfig and current is HostConfig of the container from docker daemon:
+// 1) Memory, MemorySwap, MemoryReservation, NanoCpus, CpuShares, CpuQuota, PidsLimit
+// with type "size" or "int" - MaxValue, MaxFactor
+// 2) RestartPolicy with type "restartpolicy" - AllowToUse, MaxValue for MaximumRetryCount.
+// The rules of the container policy for Devices, DeviceCgroupRules, DeviceRequests and Ulimits are applied too
+func ComplyTheUpdatePolicy(body string, current *container.HostConfig, caller identity.Identity, image string) (bool, string) {
+	rules, err := LoadRules(PathToTheUpdatePolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	var update container.UpdateConfig
+	if err := decodeBody(body, &update); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case sizeType, intType:
+			yes = complyTheLimit(update, current, nameOfKey, rule.Type, rule.Kind, valueFromCSV)
+		case restartPolicyType:
+			yes = complyTheRestartPolicy(update.RestartPolicy, rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this update policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+
+	// Devices and Ulimits are the resources too, update is judged by the same rules of the container policy as create
+	containerRules, err := LoadRules(PathToThePolicy, Scope{Caller: caller, Image: image})
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, rule := range containerRules {
+		// paths of devices are case sensitive
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+
+		var yes bool
+		switch rule.Type {
+		case deviceType:
+			yes = complyTheDevices(body, valueFromCSV)
+		case ulimitType:
+			yes = complyTheUlimits(body, strings.ToLower(rule.Key), rule.Kind, strings.ToLower(valueFromCSV))
+		default:
+			// the rest of HostConfig can't be changed by update
+			continue
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
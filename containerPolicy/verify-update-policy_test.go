@@ -0,0 +1,169 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+type UpdateTestCase struct {
+	name    string
+	body    map[string]interface{}
+	current *container.HostConfig
+	caller  identity.Identity
+	result  Result
+}
+
+func TestComplyTheUpdatePolicy(t *testing.T) {
+	PathToTheUpdatePolicy = "testdata/update_policy.csv"
+	PathToThePolicy = "testdata/kernel_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "data"}
+	pids := int64(512)
+	current := &container.HostConfig{Resources: container.Resources{Memory: 1 << 30, NanoCPUs: 1000000000, PidsLimit: &pids}}
+
+	testCases := []UpdateTestCase{
+		{
+			name:    "Double the memory",
+			body:    map[string]interface{}{"Memory": 2 << 30, "MemorySwap": -1},
+			current: current,
+			caller:  roman,
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Triple the memory",
+			body:    map[string]interface{}{"Memory": 3 << 30},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "memory"},
+		},
+		{
+			name:    "Memory above the ceiling",
+			body:    map[string]interface{}{"Memory": 16 << 30},
+			current: &container.HostConfig{Resources: container.Resources{Memory: 10 << 30}},
+			caller:  roman,
+			result:  Result{answer: false, msg: "memory"},
+		},
+		{
+			name:    "Ceiling of the team",
+			body:    map[string]interface{}{"Memory": 16 << 30},
+			current: &container.HostConfig{Resources: container.Resources{Memory: 10 << 30}},
+			caller:  anna,
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Memory of container without limit",
+			body:    map[string]interface{}{"Memory": 4 << 30},
+			current: &container.HostConfig{},
+			caller:  roman,
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Unknown current limits",
+			body:    map[string]interface{}{"Memory": 1 << 30},
+			current: nil,
+			caller:  roman,
+			result:  Result{answer: false, msg: "memory"},
+		},
+		{
+			name:    "Too many cpus",
+			body:    map[string]interface{}{"NanoCpus": 8000000000},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "nanocpus"},
+		},
+		{
+			name:    "Unlimited pids",
+			body:    map[string]interface{}{"PidsLimit": -1},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "pidslimit"},
+		},
+		{
+			name:    "Restart always",
+			body:    map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "always"}},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "restartpolicy"},
+		},
+		{
+			name:    "Ulimit above the ceiling",
+			body:    map[string]interface{}{"Ulimits": []map[string]interface{}{{"Name": "nofile", "Soft": 1024, "Hard": 1048576}}},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "ulimits.nofile"},
+		},
+		{
+			name:    "Too many retries",
+			body:    map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "on-failure", "MaximumRetryCount": 100}},
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "restartpolicy.maximumretrycount"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheUpdatePolicy(string(jsonString), testCase.current, testCase.caller, "alpine")
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestDevicesAtUpdate(t *testing.T) {
+	PathToTheUpdatePolicy = "testdata/update_policy.csv"
+	PathToThePolicy = "testdata/device_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "data"}
+	current := &container.HostConfig{}
+	body := map[string]interface{}{
+		"Devices": []map[string]string{{"PathOnHost": "/dev/fuse", "PathInContainer": "/dev/fuse", "CgroupPermissions": "rwm"}},
+	}
+
+	testCases := []UpdateTestCase{
+		{
+			name:    "Device for the team without allowlist",
+			body:    body,
+			current: current,
+			caller:  roman,
+			result:  Result{answer: false, msg: "devices"},
+		},
+		{
+			name:    "Allowed device",
+			body:    body,
+			current: current,
+			caller:  anna,
+			result:  Result{true, ""},
+		},
+		{
+			name:    "Cgroup rule of the device outside of allowlist",
+			body:    map[string]interface{}{"DeviceCgroupRules": []string{"b 8:* rwm"}},
+			current: current,
+			caller:  anna,
+			result:  Result{answer: false, msg: "devices"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheUpdatePolicy(string(jsonString), testCase.current, testCase.caller, "alpine")
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
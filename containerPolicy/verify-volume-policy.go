@@ -0,0 +1,182 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+const (
+	driverType    = "driver"
+	driverOptType = "driveropt"
+	localDriver   = "local"
+)
+
+var (
+	PathToTheVolumePolicy = "containerPolicy/volume_policy.csv"
+)
+
+// valuesOfDriverOpt splits the option into values: o=bind,ro gives bind and ro.
+// Paths are cleaned, so device=/home/roman/../../ is /
+func valuesOfDriverOpt(option string) []string {
+	var values []string
+	for _, value := range strings.Split(strings.ToLower(option), ",") {
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "/") {
+			value = path.Clean(value)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// complyTheDriverOpt checks the option of the driver, DriverOpts.<name> of the volume
+// or Options.<name> of the network, "*" of the glob matches "/" too:
+// 1) AllowToUse, if some of values doesn't match any of globs from politic - DENY
+// 2) DoesntExpectToSee, if some of values matches one of globs from politic - DENY.
+// Absent option is not checked
+func complyTheDriverOpt(driverOpts map[string]string, optionName string, kindOfPolicy string, valueFromCSV string) bool {
+	for name, option := range driverOpts {
+		if strings.ToLower(name) != optionName {
+			continue
+		}
+		for _, value := range valuesOfDriverOpt(option) {
+			if !complyTheImageSource(value, kindOfPolicy, valueFromCSV) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// complyTheVolumeRules checks the driver and its options against the rules
+func complyTheVolumeRules(rules []Rule, driver string, driverOpts map[string]string, caller identity.Identity) (bool, string) {
+	if driver == "" {
+		driver = localDriver
+	}
+
+	for _, rule := range rules {
+		nameOfKey := strings.ToLower(rule.Key)
+
+		valueFromCSV, ok := caller.Expand(rule.Value)
+		if !ok {
+			return false, rule.Name()
+		}
+		valueFromCSV = strings.ToLower(valueFromCSV)
+
+		var yes bool
+		switch rule.Type {
+		case driverType:
+			yes = complyTheImageSource(strings.ToLower(driver), rule.Kind, valueFromCSV)
+		case driverOptType:
+			yes = complyTheDriverOpt(driverOpts, strings.TrimPrefix(nameOfKey, "driveropts."), rule.Kind, valueFromCSV)
+		default:
+			log.Println("I don't know this volume policy:", rule.Type)
+			return false, rule.Name()
+		}
+		if !yes {
+			return false, rule.Name()
+		}
+	}
+	return true, ""
+}
+
+// Policy for the body of /volumes/create. The rules live at volume_policy.csv:
+// 1) Driver with type "driver" - AllowToUse, DoesntExpectToSee
+// 2) DriverOpts.<name> with type "driveropt" - AllowToUse, DoesntExpectToSee, see complyTheDriverOpt
+// DriverOpts.o,"[bind,rbind]",driveropt,DoesntExpectToSee forbids the local driver to mount the host
+func ComplyTheVolumePolicy(body string, caller identity.Identity) (bool, string) {
+	rules, err := LoadRules(PathToTheVolumePolicy, ScopeFromBody(body, caller))
+	if err != nil {
+		return false, err.Error()
+	}
+
+	var options volume.CreateOptions
+	if err := decodeBody(body, &options); err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+	return complyTheVolumeRules(rules, options.Driver, options.DriverOpts, caller)
+}
+
+// ComplyTheVolumeMounts checks the volumes created by HostConfig.Mounts of /containers/create,
+// --mount type=volume,volume-opt=o=bind,volume-opt=device=/ creates the volume the same way
+func ComplyTheVolumeMounts(body string, caller identity.Identity) (bool, string) {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return false, "Error decoding the body: " + err.Error()
+	}
+
+	for _, m := range config.hostConfig().Mounts {
+		if m.Type != mount.TypeVolume || m.VolumeOptions == nil {
+			continue
+		}
+		if !ComplyTheOwnerLabel(m.VolumeOptions.Labels, caller) {
+			return false, "labels." + OwnerLabel
+		}
+		if m.VolumeOptions.DriverConfig == nil {
+			continue
+		}
+		rules, err := LoadRules(PathToTheVolumePolicy, Scope{Caller: caller, Labels: m.VolumeOptions.Labels})
+		if err != nil {
+			return false, err.Error()
+		}
+		driverConfig := m.VolumeOptions.DriverConfig
+		if yes, failedPolicy := complyTheVolumeRules(rules, driverConfig.Name, driverConfig.Options, caller); !yes {
+			return false, failedPolicy
+		}
+	}
+	return true, ""
+}
+
+// NamedVolumes returns the names of the volumes the container mounts
+// from HostConfig.Binds (name:/data) and HostConfig.Mounts
+func NamedVolumes(body string) []string {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, bind := range config.hostConfig().Binds {
+		source := strings.SplitN(bind, ":", 2)[0]
+		if !strings.HasPrefix(source, "/") && strings.Contains(bind, ":") {
+			names = append(names, source)
+		}
+	}
+	for _, m := range config.hostConfig().Mounts {
+		if m.Type == mount.TypeVolume && m.Source != "" {
+			names = append(names, m.Source)
+		}
+	}
+	return names
+}
+
+// ContainersOfVolumesFrom returns the containers whose volumes the new container mounts:
+// --volumes-from <name|id>[:ro|:rw]
+func ContainersOfVolumesFrom(body string) []string {
+	config, err := decodeContainerBody(body)
+	if err != nil {
+		return nil
+	}
+
+	var containers []string
+	for _, from := range config.hostConfig().VolumesFrom {
+		containers = append(containers, strings.SplitN(from, ":", 2)[0])
+	}
+	return containers
+}
+
+// VolumeNameFromBody returns Name of the create body or of the volume docker daemon returned.
+// Empty name means docker will generate it
+func VolumeNameFromBody(body string) string {
+	var config struct {
+		Name string
+	}
+	_ = json.Unmarshal([]byte(body), &config)
+	return config.Name
+}
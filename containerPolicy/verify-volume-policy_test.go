@@ -0,0 +1,100 @@
+package containerpolicy
+
+import (
+	"encoding/json"
+	fmt2 "fmt"
+	"testing"
+
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplyTheVolumePolicy(t *testing.T) {
+	PathToTheVolumePolicy = "testdata/volume_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := identity.Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001", Team: "storage"}
+
+	testCases := []AdmitTestCase{
+		{
+			name:   "Volume of the local driver",
+			body:   map[string]interface{}{"Name": "data"},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Volume of other driver",
+			body:   map[string]interface{}{"Name": "data", "Driver": "rexray/ebs"},
+			caller: roman,
+			result: Result{answer: false, msg: "driver"},
+		},
+		{
+			name:   "Bind of the root",
+			body:   map[string]interface{}{"Name": "root", "DriverOpts": map[string]string{"type": "none", "o": "bind", "device": "/"}},
+			caller: roman,
+			result: Result{answer: false, msg: "driveropts.o"},
+		},
+		{
+			name:   "Read only rbind",
+			body:   map[string]interface{}{"Name": "root", "DriverOpts": map[string]string{"type": "none", "o": "ro,RBIND", "device": "/"}},
+			caller: roman,
+			result: Result{answer: false, msg: "driveropts.o"},
+		},
+		{
+			name:   "Tmpfs volume",
+			body:   map[string]interface{}{"Name": "cache", "DriverOpts": map[string]string{"type": "tmpfs", "o": "size=100m", "device": "tmpfs"}},
+			caller: roman,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Volume of other filesystem",
+			body:   map[string]interface{}{"Name": "disk", "DriverOpts": map[string]string{"type": "ext4", "device": "/dev/sda1"}},
+			caller: roman,
+			result: Result{answer: false, msg: "driveropts.type"},
+		},
+		{
+			name:   "Bind of the directory of the user",
+			body:   map[string]interface{}{"Name": "data", "DriverOpts": map[string]string{"type": "none", "o": "bind", "device": "/srv/anna/data"}},
+			caller: anna,
+			result: Result{true, ""},
+		},
+		{
+			name:   "Bind escapes the directory of the user",
+			body:   map[string]interface{}{"Name": "data", "DriverOpts": map[string]string{"type": "none", "o": "bind", "device": "/srv/anna/../../etc"}},
+			caller: anna,
+			result: Result{answer: false, msg: "driveropts.device"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			jsonString, err := json.Marshal(testCase.body)
+			if err != nil {
+				fmt2.Println("Error during Marshal into JSON:", err)
+				return
+			}
+			response, msg := ComplyTheVolumePolicy(string(jsonString), testCase.caller)
+			assert.Equal(t, testCase.result, Result{response, msg})
+		})
+	}
+}
+
+func TestComplyTheVolumeMounts(t *testing.T) {
+	PathToTheVolumePolicy = "testdata/volume_policy.csv"
+	roman := identity.Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+
+	body := `{"Image":"alpine","HostConfig":{"Mounts":[{"Type":"volume","Source":"data","Target":"/data"}]}}`
+	yes, msg := ComplyTheVolumeMounts(body, roman)
+	assert.Equal(t, Result{true, ""}, Result{yes, msg})
+
+	body = `{"Image":"alpine","HostConfig":{"Mounts":[{"Type":"volume","Source":"root","Target":"/host",` +
+		`"VolumeOptions":{"DriverConfig":{"Name":"local","Options":{"type":"none","o":"bind","device":"/"}}}}]}}`
+	yes, msg = ComplyTheVolumeMounts(body, roman)
+	assert.Equal(t, Result{answer: false, msg: "driveropts.o"}, Result{yes, msg})
+}
+
+func TestNamedVolumes(t *testing.T) {
+	body := `{"Image":"alpine","HostConfig":{"Binds":["data:/data","/home/roman:/work","/anonymous"],` +
+		`"Mounts":[{"Type":"volume","Source":"cache","Target":"/cache"},{"Type":"volume","Target":"/tmp"},{"Type":"bind","Source":"/srv","Target":"/srv"}]}}`
+	assert.Equal(t, []string{"data", "cache"}, NamedVolumes(body))
+}
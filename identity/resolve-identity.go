@@ -0,0 +1,83 @@
+package identity
+
+import (
+	"encoding/csv"
+	"log"
+	"os"
+	"strings"
+)
+
+var (
+	PathToTheUsers = "identity/users.csv"
+)
+
+// Who stands behind the AuthHeader. users.csv looks like:
+// <sha256 of AuthHeader>,<user>,<uid>,<team>,<role>
+type Identity struct {
+	KeyHash string
+	User    string
+	UID     string
+	Team    string
+	Role    string
+}
+
+// Resolve looks for the owner of keyHash at the users file.
+// Unknown caller gets the Identity with KeyHash only
+func Resolve(keyHash string) Identity {
+	caller := Identity{KeyHash: keyHash}
+	if keyHash == "" {
+		return caller
+	}
+
+	file, err := os.Open(PathToTheUsers)
+	if err != nil {
+		log.Println("Error opening the users file:", err)
+		return caller
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// team and role are optional
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Println("Error reading the users file:", err)
+		return caller
+	}
+
+	for _, row := range records {
+		if len(row) < 3 || row[0] != keyHash {
+			continue
+		}
+		caller.User = row[1]
+		caller.UID = row[2]
+		if len(row) > 3 {
+			caller.Team = row[3]
+		}
+		if len(row) > 4 {
+			caller.Role = row[4]
+		}
+		break
+	}
+	return caller
+}
+
+// Expand puts the caller into ${user}, ${uid} and ${team} of value.
+// Return false if value needs something we don't know about the caller
+func (caller Identity) Expand(value string) (string, bool) {
+	variables := map[string]string{
+		"${user}": caller.User,
+		"${uid}":  caller.UID,
+		"${team}": caller.Team,
+	}
+	for variable, replacement := range variables {
+		if !strings.Contains(value, variable) {
+			continue
+		}
+		if replacement == "" {
+			return value, false
+		}
+		value = strings.ReplaceAll(value, variable, replacement)
+	}
+	return value, true
+}
@@ -0,0 +1,43 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	PathToTheUsers = "testdata/users.csv"
+
+	roman := Resolve("7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713")
+	assert.Equal(t, Identity{KeyHash: "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713", User: "roman", UID: "1000", Team: "infra", Role: "developer"}, roman)
+
+	anna := Resolve("0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887")
+	assert.Equal(t, Identity{KeyHash: "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887", User: "anna", UID: "1001"}, anna)
+
+	stranger := Resolve("e51cc6373acd45d624e930cb8162cbcc")
+	assert.Equal(t, Identity{KeyHash: "e51cc6373acd45d624e930cb8162cbcc"}, stranger)
+}
+
+func TestExpand(t *testing.T) {
+	roman := Identity{KeyHash: "7c3aa42f", User: "roman", UID: "1000", Team: "infra"}
+	anna := Identity{KeyHash: "0c5de4e4", User: "anna", UID: "1001"}
+
+	value, ok := roman.Expand("/home/${user}:/work")
+	assert.True(t, ok)
+	assert.Equal(t, "/home/roman:/work", value)
+
+	value, ok = roman.Expand("/${team}.slice/${uid}")
+	assert.True(t, ok)
+	assert.Equal(t, "/infra.slice/1000", value)
+
+	_, ok = anna.Expand("${team}-*")
+	assert.False(t, ok)
+
+	_, ok = Identity{KeyHash: "e51cc637"}.Expand("${user}-*")
+	assert.False(t, ok)
+
+	value, ok = anna.Expand("[host]")
+	assert.True(t, ok)
+	assert.Equal(t, "[host]", value)
+}
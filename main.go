@@ -7,6 +7,8 @@ import (
 	"os/user"
 	"strconv"
 
+	containerpolicy "github.com/casbin/casbin-authz-plugin/containerPolicy"
+	"github.com/casbin/casbin-authz-plugin/identity"
 	"github.com/casbin/casbin-authz-plugin/plugin"
 	"github.com/docker/go-plugins-helpers/authorization"
 	"github.com/joho/godotenv"
@@ -19,6 +21,17 @@ const (
 var (
 	AdminToken      string
 	containerPolicy = flag.String("container policy", "containerPolicy/container_policy.csv", "Specifies the container policy file")
+	execPolicy      = flag.String("exec-policy", "containerPolicy/exec_policy.csv", "Specifies the exec policy file")
+	updatePolicy    = flag.String("update-policy", "containerPolicy/update_policy.csv", "Specifies the update policy file")
+	archivePolicy   = flag.String("archive-policy", "containerPolicy/archive_policy.csv", "Specifies the archive policy file")
+	imagePolicy     = flag.String("image-policy", "containerPolicy/image_policy.csv", "Specifies the image policy file")
+	digestDenyList  = flag.String("digest-denylist", "containerPolicy/digest_denylist.csv", "Specifies the file with the digests flagged by the scanner")
+	imageConfig     = flag.String("image-config-policy", "containerPolicy/image_config_policy.csv", "Specifies the image config policy file")
+	buildPolicy     = flag.String("build-policy", "containerPolicy/build_policy.csv", "Specifies the build policy file")
+	volumePolicy    = flag.String("volume-policy", "containerPolicy/volume_policy.csv", "Specifies the volume policy file")
+	networkPolicy   = flag.String("network-policy", "containerPolicy/network_policy.csv", "Specifies the network policy file")
+	pluginPolicy    = flag.String("plugin-policy", "containerPolicy/plugin_policy.csv", "Specifies the plugin policy file")
+	users           = flag.String("users", "identity/users.csv", "Specifies the file with the owners of AuthHeaders")
 )
 
 func main() {
@@ -26,6 +39,31 @@ func main() {
 	pwd, _ := os.Getwd()
 	log.Println("Current directory:", pwd)
 	log.Println("Container policy:", *containerPolicy)
+	containerpolicy.PathToThePolicy = *containerPolicy
+	log.Println("Exec policy:", *execPolicy)
+	containerpolicy.PathToTheExecPolicy = *execPolicy
+	log.Println("Update policy:", *updatePolicy)
+	containerpolicy.PathToTheUpdatePolicy = *updatePolicy
+	log.Println("Archive policy:", *archivePolicy)
+	containerpolicy.PathToTheArchivePolicy = *archivePolicy
+	log.Println("Image policy:", *imagePolicy)
+	containerpolicy.PathToTheImagePolicy = *imagePolicy
+	log.Println("Digest deny-list:", *digestDenyList)
+	containerpolicy.PathToTheDigestDenyList = *digestDenyList
+	log.Println("Image config policy:", *imageConfig)
+	containerpolicy.PathToTheImageConfigPolicy = *imageConfig
+	log.Println("Build policy:", *buildPolicy)
+	containerpolicy.PathToTheBuildPolicy = *buildPolicy
+	log.Println("Volume policy:", *volumePolicy)
+	containerpolicy.PathToTheVolumePolicy = *volumePolicy
+	log.Println("Network policy:", *networkPolicy)
+	containerpolicy.PathToTheNetworkPolicy = *networkPolicy
+	log.Println("Plugin policy:", *pluginPolicy)
+	containerpolicy.PathToThePluginPolicy = *pluginPolicy
+	log.Println("Users:", *users)
+	identity.PathToTheUsers = *users
+	// the rules scoped by image judge the image ID by its names
+	containerpolicy.ImageNamesOfID = plugin.ImageNames
 
 	err := godotenv.Load()
 	if err != nil {
@@ -33,6 +71,17 @@ func main() {
 	}
 	AdminToken = os.Getenv("ADMIN_TOKEN")
 	plugin.DefineAdminToken(AdminToken)
+	// OWNER_FILTER_ON_LIST="true" denies docker ps without the filter of the owner
+	if ownerFilterOnList, err := strconv.ParseBool(os.Getenv("OWNER_FILTER_ON_LIST")); err == nil {
+		log.Println("Owner filter on the list of containers:", ownerFilterOnList)
+		plugin.DefineOwnerFilterOnList(ownerFilterOnList)
+	}
+
+	// DEFAULT_LOG_DRIVER="local" is log-driver of daemon.json, the log options without --log-driver are judged for it
+	if defaultLogDriver := os.Getenv("DEFAULT_LOG_DRIVER"); defaultLogDriver != "" {
+		log.Println("Default log driver:", defaultLogDriver)
+		containerpolicy.DefaultLogDriver = defaultLogDriver
+	}
 
 	authPlugin, err := plugin.NewPlugin()
 	if err != nil {
@@ -2,23 +2,49 @@ package plugin
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
 
 	containerpolicy "github.com/casbin/casbin-authz-plugin/containerPolicy"
+	"github.com/casbin/casbin-authz-plugin/identity"
 	"github.com/casbin/casbin/v2"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 	"github.com/docker/go-plugins-helpers/authorization"
 )
 
 const (
 	creationContainerAPI   = "/containers/create"
+	pullImageAPI           = "/images/create"
+	actionWithImageAPI     = "/images/"
+	buildImageAPI          = "/build"
+	volumesAPI             = "/volumes"
+	createVolumeAPI        = "/volumes/create"
+	pruneVolumesAPI        = "/volumes/prune"
+	networksAPI            = "/networks"
+	createNetworkAPI       = "/networks/create"
+	pruneBuildAPI          = "/build/prune"
+	listContainersAPI      = "/containers/json"
+	servicesAPI            = "/services"
+	createServiceAPI       = "/services/create"
+	commitAPI              = "/commit"
+	pluginsAPI             = "/plugins"
+	pullPluginAPI          = "/plugins/pull"
+	createPluginAPI        = "/plugins/create"
+	secretsAPI             = "/secrets"
+	configsAPI             = "/configs"
+	lengthOfNetworkID      = 64
+	lengthOfSwarmID        = 25
+	lengthOfImageID        = 71
 	actionWithContainerAPI = "/containers/"
 	execAtContainerAPI     = "/exec/"
 	headerWithToken        = "AuthHeader"
@@ -27,19 +53,43 @@ const (
 )
 
 var (
-	AdminToken          string
+	AdminToken string
+	// AuthHeader of the docker client of the plugin itself, docker daemon asks the plugin about its requests too
+	pluginToken = newPluginToken()
+	// Non-admins have to list only their containers, see DefineOwnerFilterOnList
+	OwnerFilterOnList   bool
 	IDAndHashKeyMapping = make(map[string]string)
 	IDAndNameMapping    = make(map[string]string)
-	AllowToDo           = []string{
+	// The whole name of the image with the tag and who pulled, built or tagged it
+	ImageAndHashKeyMapping = make(map[string]string)
+	imageActionRegex       = regexp.MustCompile(`^/images/(.+)/(tag|push)$`)
+	// The name of the volume and who created it
+	VolumeAndHashKeyMapping = make(map[string]string)
+	// The name and the ID of the network and who created it
+	NetworkAndHashKeyMapping = make(map[string]string)
+	// The prunes of docker remove the resources of everyone, the prune of volumes is only for admin
+	pruneRegex = regexp.MustCompile(`^/(containers|images|networks|build)/prune$`)
+	// The name and the ID of the secret or the config of swarm and who created it
+	SecretAndHashKeyMapping = make(map[string]string)
+	ConfigAndHashKeyMapping = make(map[string]string)
+	// The name and the ID of the service of swarm and who created it
+	ServiceAndHashKeyMapping = make(map[string]string)
+	// This plugin at docker daemon, --authorization-plugin=container-authz-plugin
+	AuthzPluginName    = "container-authz-plugin"
+	pluginActionRegex  = regexp.MustCompile(`^/plugins/(.+)/(disable|upgrade|set)$`)
+	upgradePluginRegex = regexp.MustCompile(`^/plugins/.+/upgrade$`)
+	// The ID or the prefix of the ID of the plugin or the image
+	idRegex            = regexp.MustCompile(`^[0-9a-f]+$`)
+	updateServiceRegex = regexp.MustCompile(`^/services/([^/]+)/update$`)
+	networkActionRegex = regexp.MustCompile(`^/networks/([^/]+)/(connect|disconnect)$`)
+	AllowToDo          = []string{
 		"/_ping",
 		"/images/json",
 		"/containers/json?all=1",
 		"/containers/json",
 	}
 	ForbiddenToDo = []string{
-		"/commit",
-		"/volumes",
-		"/plugins",
+		"/swarm",
 	}
 )
 
@@ -60,6 +110,35 @@ func DefineAdminToken(token string) {
 	AdminToken = token
 }
 
+// DefineOwnerFilterOnList turns on the filter of the owner for /containers/json.
+// The plugin can't hide other's containers in the response, so the list without the filter is denied
+func DefineOwnerFilterOnList(yes bool) {
+	OwnerFilterOnList = yes
+}
+
+// newPluginToken makes the random AuthHeader for the docker client of the plugin,
+// it lives only in the memory of the plugin
+func newPluginToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		log.Fatalf("Failed to make the token of the plugin: %v", err)
+	}
+	return hex.EncodeToString(token)
+}
+
+// newDockerClient makes the client which sends pluginToken, so the plugin doesn't deny its own requests
+func newDockerClient() (*client.Client, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation(),
+		client.WithHTTPHeaders(map[string]string{headerWithToken: pluginToken}))
+}
+
+// IsItPluginClient checks the request is sent by newDockerClient.
+// The plugin only lists and inspects, so only GET is allowed for it
+func IsItPluginClient(req authorization.Request) bool {
+	key := req.RequestHeaders[headerWithToken]
+	return req.RequestMethod == http.MethodGet && subtle.ConstantTimeCompare([]byte(key), []byte(pluginToken)) == 1
+}
+
 func IsItAdmin(keyHash string) bool {
 	if keyHash == AdminToken {
 		log.Println("Bypass for admin")
@@ -79,6 +158,111 @@ func AllowMakeTheAction(keyHashFromMapa string, keyHash string) bool {
 	}
 }
 
+// AllowManageTheImage allows the owner of the image, the team of the owner and admin.
+// Nobody but admin can manage the image without the owner
+func AllowManageTheImage(keyHashFromMapa string, keyHash string) bool {
+	if AllowMakeTheAction(keyHashFromMapa, keyHash) {
+		return true
+	}
+	if keyHashFromMapa == "" {
+		return false
+	}
+	owner := identity.Resolve(keyHashFromMapa)
+	return owner.Team != "" && owner.Team == identity.Resolve(keyHash).Team
+}
+
+// ImagesOfThePush returns the recorded images the push will send.
+// docker push --all-tags sends no tag, so all tags of the repository are pushed
+func ImagesOfThePush(repository string, tag string) []string {
+	if tag != "" {
+		image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(repository, tag))
+		return []string{image}
+	}
+	prefix := strings.TrimSuffix(containerpolicy.NormalizeImage(repository), ":latest") + ":"
+	var images []string
+	for image := range ImageAndHashKeyMapping {
+		if strings.HasPrefix(image, prefix) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// ownerOfResource looks for the owner by the name, the ID or the prefix of the ID like docker daemon does
+func ownerOfResource(mapa map[string]string, nameOrID string, lengthOfID int) (string, bool) {
+	if keyHash, found := mapa[nameOrID]; found {
+		return keyHash, true
+	}
+	for key, keyHash := range mapa {
+		if len(key) == lengthOfID && strings.HasPrefix(key, nameOrID) {
+			return keyHash, true
+		}
+	}
+	return "", false
+}
+
+// imageKey returns the key of ImageAndHashKeyMapping for the image: the whole name with the tag
+// or the ID sha256:..., docker daemon finds the image by the prefix of the ID too
+func imageKey(nameOrID string) string {
+	name := containerpolicy.NormalizeImage(nameOrID)
+	if _, found := ImageAndHashKeyMapping[name]; found {
+		return name
+	}
+	if id := strings.TrimPrefix(nameOrID, "sha256:"); idRegex.MatchString(id) {
+		for key := range ImageAndHashKeyMapping {
+			if len(key) == lengthOfImageID && strings.HasPrefix(key, "sha256:"+id) {
+				return key
+			}
+		}
+	}
+	return name
+}
+
+// ImageOwner looks for the owner of the image by the name, the ID or the prefix of the ID
+func ImageOwner(nameOrID string) (string, bool) {
+	keyHash, found := ImageAndHashKeyMapping[imageKey(nameOrID)]
+	return keyHash, found
+}
+
+// ImageNames returns the names of the image to judge it by the image policy.
+// The image referenced by the ID is judged by all its tags, docker daemon tells them
+func ImageNames(nameOrID string) ([]string, error) {
+	if !idRegex.MatchString(strings.TrimPrefix(nameOrID, "sha256:")) {
+		return []string{containerpolicy.NormalizeImage(nameOrID)}, nil
+	}
+	inspect, err := InspectImage(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if len(inspect.RepoTags) == 0 {
+		return nil, fmt.Errorf("the image %s has no tags", nameOrID)
+	}
+	return inspect.RepoTags, nil
+}
+
+// recordImageID records the owner of the ID of the image, so the image can be removed by the ID.
+// The ID keeps its first owner, others can pull or tag the same image
+func recordImageID(image string, keyHash string) {
+	inspect, err := InspectImage(image)
+	if err != nil {
+		log.Println("[InspectImage] Error occurred:", err)
+		return
+	}
+	if _, found := ImageAndHashKeyMapping[inspect.ID]; !found && inspect.ID != "" {
+		ImageAndHashKeyMapping[inspect.ID] = keyHash
+	}
+}
+
+// NetworkOwner looks for the owner of the network by the name, the ID or the prefix of the ID
+func NetworkOwner(network string) (string, bool) {
+	return ownerOfResource(NetworkAndHashKeyMapping, network, lengthOfNetworkID)
+}
+
+// SwarmObjectOwner looks for the owner of the secret or the config by the name, the ID or the prefix of the ID
+func SwarmObjectOwner(mapa map[string]string, nameOrID string) (string, bool) {
+	return ownerOfResource(mapa, nameOrID, lengthOfSwarmID)
+}
+
 func DefineContainerID(obj string) string {
 	partsOfApi := strings.Split(obj, "/")
 	containerID := partsOfApi[2]
@@ -120,7 +304,7 @@ func DefineContainerID(obj string) string {
 // We also solve the problem hanging in air containers
 func CheckDatabaseAndMakeMapa() error {
 	ctx := context.Background()
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	cli, err := newDockerClient()
 	if err != nil {
 		return err
 	}
@@ -172,6 +356,115 @@ func CheckDatabaseAndMakeMapa() error {
 	return nil
 }
 
+// InspectContainer asks docker daemon about the container,
+// the policies need it when there is no create body
+func InspectNetwork(network string) (types.NetworkResource, error) {
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return types.NetworkResource{}, err
+	}
+	defer cli.Close()
+
+	return cli.NetworkInspect(ctx, network, types.NetworkInspectOptions{})
+}
+
+func InspectContainer(containerID string) (types.ContainerJSON, error) {
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	defer cli.Close()
+
+	inspect, err := cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return types.ContainerJSON{}, err
+	}
+	if inspect.ContainerJSONBase == nil || inspect.Config == nil {
+		return types.ContainerJSON{}, fmt.Errorf("docker daemon didn't tell about the container %s", containerID)
+	}
+	return inspect, nil
+}
+
+// ContainerImage returns the image of the container for the rules scoped by image.
+// Return empty string if docker daemon doesn't know the container
+func ContainerImage(containerID string) string {
+	inspect, err := InspectContainer(containerID)
+	if err != nil {
+		log.Println("[ContainerImage] Error occurred:", err)
+		return ""
+	}
+	return inspect.Config.Image
+}
+
+// InspectImage asks docker daemon about the image the container is created from
+func InspectImage(image string) (types.ImageInspect, error) {
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return types.ImageInspect{}, err
+	}
+	defer cli.Close()
+
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, image)
+	return inspect, err
+}
+
+func InspectPlugin(plugin string) (*types.Plugin, error) {
+	ctx := context.Background()
+	cli, err := newDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	inspect, _, err := cli.PluginInspectWithRaw(ctx, plugin)
+	return inspect, err
+}
+
+// IsItAuthzPlugin checks the plugin is this authorization plugin by the name with or without the tag
+// and by the ID docker daemon knows. The plugin we can't find by the ID may be this one
+func IsItAuthzPlugin(nameOrID string) bool {
+	if containerpolicy.PluginName(nameOrID) == AuthzPluginName {
+		return true
+	}
+	// docker daemon finds the plugin by the ID and by the prefix of the ID too
+	if !idRegex.MatchString(nameOrID) {
+		return false
+	}
+	inspect, err := InspectPlugin(nameOrID)
+	if err != nil {
+		log.Println("[InspectPlugin] Error occurred:", err)
+		return true
+	}
+	return containerpolicy.PluginName(inspect.Name) == AuthzPluginName
+}
+
+// ImageDigests returns the digests of the inspected image:
+// the digests of the manifests from RepoDigests and the ID of the image
+func ImageDigests(inspect types.ImageInspect) []string {
+	digests := []string{inspect.ID}
+	for _, repoDigest := range inspect.RepoDigests {
+		// alpine@sha256:...
+		parts := strings.SplitN(repoDigest, "@", 2)
+		if len(parts) == 2 {
+			digests = append(digests, parts[1])
+		}
+	}
+	return digests
+}
+
+// rawQuery is the query docker daemon gets. url.QueryUnescape of the request URI decodes the query twice,
+// then "x=%26fromImage%3Dalpine" hides the real fromImage
+func rawQuery(requestURI string) url.Values {
+	rawURL, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return url.Values{}
+	}
+	return rawURL.Query()
+}
+
 func CalculateHash(key string) string {
 	hasher := sha256.New()
 
@@ -199,11 +492,45 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 	}
 
 	obj := reqURL.String()
-	reqBody, _ := url.QueryUnescape(string(req.RequestBody))
+	// the policies decode the body docker daemon gets, "%22" inside of a string must stay a string
+	reqBody := string(req.RequestBody)
+	query := rawQuery(req.RequestURI)
 
 	// Cropping the version /v1.42/containers/...
 	re := regexp.MustCompile(`/v\d+\.\d+/`)
 	obj = re.ReplaceAllString(obj, "/")
+	// Path without a query, docker run --name sends /containers/create?name=...
+	api := re.ReplaceAllString(reqURL.Path, "/")
+
+	// The plugin asks docker daemon about the containers, images and plugins
+	if IsItPluginClient(req) {
+		return authorization.Response{Allow: true}
+	}
+
+	if api == listContainersAPI {
+		if !OwnerFilterOnList {
+			return authorization.Response{Allow: true}
+		}
+		// without AuthHeader we don't know whose containers to show
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		// filters is JSON, so we parse the query docker daemon gets
+		caller := identity.Resolve(keyHash)
+		listURL, err := url.ParseRequestURI(req.RequestURI)
+		if err != nil || !containerpolicy.ComplyTheOwnerFilter(listURL.Query().Get("filters"), caller) {
+			msg := fmt.Sprintf("You can list only your containers, use docker ps %s", containerpolicy.OwnerFilterHint(caller))
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. " + msg}
+		}
+		return authorization.Response{Allow: true}
+	}
 
 	for _, j := range AllowToDo {
 		if obj == j {
@@ -211,6 +538,37 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 		}
 	}
 
+	// Plugins are judged before the bypass for admin, so the mistake of admin can't switch off the authorization
+	if api == pluginsAPI || strings.HasPrefix(api, pluginsAPI+"/") {
+		name := ""
+		if matches := pluginActionRegex.FindStringSubmatch(api); matches != nil {
+			name = matches[1]
+		} else if req.RequestMethod == http.MethodDelete {
+			name = strings.TrimPrefix(api, pluginsAPI+"/")
+		}
+		if name != "" && IsItAuthzPlugin(name) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Nobody can switch off the authorization plugin: " + name}
+		}
+
+		keyHash := CalculateHash(req.RequestHeaders[headerWithToken])
+		if yes := IsItAdmin(keyHash); !yes {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin: " + obj}
+		}
+
+		switch {
+		case api == createPluginAPI:
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The plugin from the tarball can't be judged by the plugin policy"}
+		case api == pullPluginAPI || upgradePluginRegex.MatchString(api):
+			remote := query.Get("remote")
+			yes, failedPolicy := containerpolicy.ComplyThePluginPolicy(remote, reqBody, identity.Resolve(keyHash))
+			if !yes {
+				msg := fmt.Sprintf("Plugin %s does not comply with the plugin policy: %s", remote, failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
 	for _, j := range ForbiddenToDo {
 		keyHash := CalculateHash(req.RequestHeaders[headerWithToken])
 		if yes := IsItAdmin(keyHash); yes {
@@ -221,22 +579,593 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 		}
 	}
 
-	updateRegex := regexp.MustCompile(`/containers/[^/]+/update$`)
-	if obj == creationContainerAPI || updateRegex.MatchString(obj) {
+	if api == creationContainerAPI {
 
+		keyHash := ""
 		if req.RequestHeaders[headerWithToken] != "" {
-			keyHash := CalculateHash(req.RequestHeaders[headerWithToken])
+			keyHash = CalculateHash(req.RequestHeaders[headerWithToken])
 			if yes := IsItAdmin(keyHash); yes {
 				return authorization.Response{Allow: true}
 			}
 		}
 
 		// Allow to create without AuthHeader, because we don't have the container ID at this step
-		yes, failedPolicy := containerpolicy.ComplyTheContainerPolicy(reqBody)
+		caller := identity.Resolve(keyHash)
+		yes, failedPolicy := containerpolicy.ComplyTheContainerPolicy(reqBody, caller)
 		if !yes {
 			msg := fmt.Sprintf("Container Body does not comply with the container policy: %s", failedPolicy)
 			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
 		}
+
+		// docker daemon doesn't create the container without the image
+		scope := containerpolicy.ScopeFromBody(reqBody, caller)
+		if scope.Image != "" {
+			yes, failedPolicy = containerpolicy.ComplyTheImagePolicy(scope.Image, scope)
+			if !yes {
+				msg := fmt.Sprintf("Container Image does not comply with the image policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+
+			// The image which is not pulled yet can't be inspected,
+			// docker CLI pulls it and sends create again. Other errors hide the image from the policies
+			var digests []string
+			inspect, err := InspectImage(scope.Image)
+			if err != nil {
+				log.Println("[InspectImage] Error occurred:", err)
+				if !client.IsErrNotFound(err) {
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't inspect the image: " + scope.Image}
+				}
+			} else {
+				digests = ImageDigests(inspect)
+			}
+			if digest := containerpolicy.DigestFromImage(scope.Image); digest != "" {
+				digests = append(digests, digest)
+			}
+			yes, failedPolicy = containerpolicy.ComplyTheDigestDenyList(digests)
+			if !yes {
+				msg := fmt.Sprintf("Container Image is on the digest deny-list: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+
+			// the container gets the labels of the image
+			if err == nil && inspect.Config != nil && !containerpolicy.ComplyTheOwnerLabel(inspect.Config.Labels, caller) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+			}
+
+			if err == nil {
+				yes, failedPolicy = containerpolicy.ComplyTheImageConfigPolicy(reqBody, inspect, caller)
+				if !yes {
+					msg := fmt.Sprintf("Container Image does not comply with the image config policy: %s", failedPolicy)
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+				}
+			}
+		}
+
+		yes, failedPolicy = containerpolicy.ComplyTheVolumeMounts(reqBody, caller)
+		if !yes {
+			msg := fmt.Sprintf("Container Mounts do not comply with the volume policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+		for _, network := range containerpolicy.NetworksOfContainer(reqBody) {
+			keyHashFromMapa, found := NetworkOwner(network)
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: " + network}
+			}
+		}
+		for _, name := range containerpolicy.NamedVolumes(reqBody) {
+			keyHashFromMapa, found := VolumeAndHashKeyMapping[name]
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: " + name}
+			}
+		}
+
+		// the container which joins the namespaces of other's container sees its network and processes,
+		// --volumes-from mounts the volumes of other's container
+		containers := append(containerpolicy.ContainersOfNamespaces(reqBody), containerpolicy.ContainersOfVolumesFrom(reqBody)...)
+		if len(containers) > 0 {
+			err := CheckDatabaseAndMakeMapa()
+			if err != nil {
+				log.Println("[CheckDatabaseAndMakeMapa] Error occurred:", err)
+			}
+			for _, nameOrID := range containers {
+				// the name docker daemon doesn't know or the ID of nobody
+				containerID := DefineContainerID(actionWithContainerAPI + nameOrID)
+				keyHashFromMapa, found := IDAndHashKeyMapping[containerID]
+				if nameOrID == "" || containerID == trash || !found || !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your container: " + nameOrID}
+				}
+			}
+		}
+
+		if !containerpolicy.ComplyTheOwnerLabel(containerpolicy.LabelsFromBody(reqBody), caller) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+		}
+
+		yes, failedPolicy = containerpolicy.ComplyTheLabelPolicy(reqBody, caller)
+		if !yes {
+			msg := fmt.Sprintf("Container Labels do not comply with the container policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+
+		yes, failedPolicy = containerpolicy.ComplyTheNamingPolicy(query.Get("name"), caller)
+		if !yes {
+			msg := fmt.Sprintf("Container Name does not comply with the container policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker import names the image from the tarball by repo and tag, the name is judged like the pull
+	if api == pullImageAPI && query.Get("fromSrc") != "" {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		// the import without repo makes the image without the name
+		if query.Get("repo") == "" {
+			return authorization.Response{Allow: true}
+		}
+		image := containerpolicy.ImageFromPull(query.Get("repo"), query.Get("tag"))
+		yes, failedPolicy := containerpolicy.ComplyTheImagePolicy(image, containerpolicy.Scope{Caller: identity.Resolve(keyHash), Image: image})
+		if !yes {
+			msg := fmt.Sprintf("Image %s does not comply with the image policy: %s", image, failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+		if keyHashFromMapa, found := ImageAndHashKeyMapping[containerpolicy.NormalizeImage(image)]; found && !AllowManageTheImage(keyHashFromMapa, keyHash) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: " + image}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// fromSrc is the import of the tarball, it isn't the pull
+	if api == pullImageAPI && query.Get("fromImage") != "" {
+		keyHash := ""
+		if req.RequestHeaders[headerWithToken] != "" {
+			keyHash = CalculateHash(req.RequestHeaders[headerWithToken])
+			if yes := IsItAdmin(keyHash); yes {
+				return authorization.Response{Allow: true}
+			}
+		}
+
+		image := containerpolicy.ImageFromPull(query.Get("fromImage"), query.Get("tag"))
+		yes, failedPolicy := containerpolicy.ComplyTheImagePolicy(image, containerpolicy.Scope{Caller: identity.Resolve(keyHash), Image: image})
+		if !yes {
+			msg := fmt.Sprintf("Image %s does not comply with the image policy: %s", image, failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+
+		// the digest of the tag is unknown until the pull, the create will check it
+		if digest := containerpolicy.DigestFromImage(image); digest != "" {
+			yes, failedPolicy = containerpolicy.ComplyTheDigestDenyList([]string{digest})
+			if !yes {
+				msg := fmt.Sprintf("Image %s is on the digest deny-list: %s", image, failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// Only admin can prune everything, others have to restrict the prune by the filter of the owner
+	if pruneRegex.MatchString(api) {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		// the build cache has no labels
+		if api == pruneBuildAPI {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune the build cache"}
+		}
+		// filters is JSON, so we parse the query docker daemon gets
+		pruneURL, err := url.ParseRequestURI(req.RequestURI)
+		caller := identity.Resolve(keyHash)
+		if err != nil || !containerpolicy.ComplyTheOwnerFilter(pruneURL.Query().Get("filters"), caller) {
+			msg := fmt.Sprintf("Only admin can prune without the filter of the owner, use %s", containerpolicy.OwnerFilterHint(caller))
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. " + msg}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker volume ls is allowed for everyone
+	if strings.HasPrefix(api, volumesAPI+"/") {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		switch api {
+		case pruneVolumesAPI:
+			// the data of the volume can't be restored, even with the filter of the owner
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune volumes"}
+		case createVolumeAPI:
+			yes, failedPolicy := containerpolicy.ComplyTheVolumePolicy(reqBody, identity.Resolve(keyHash))
+			if !yes {
+				msg := fmt.Sprintf("Volume Body does not comply with the volume policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+			if !containerpolicy.ComplyTheOwnerLabel(containerpolicy.LabelsFromBody(reqBody), identity.Resolve(keyHash)) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+			}
+			// create of the existing volume returns it
+			name := containerpolicy.VolumeNameFromBody(reqBody)
+			keyHashFromMapa, found := VolumeAndHashKeyMapping[name]
+			if name != "" && found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: " + name}
+			}
+		default:
+			// inspect and remove, the volume without the owner is only for admin
+			name := strings.TrimPrefix(api, volumesAPI+"/")
+			if allow := AllowMakeTheAction(VolumeAndHashKeyMapping[name], keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: " + name}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker network ls and inspect are allowed for everyone
+	if strings.HasPrefix(api, networksAPI+"/") && (req.RequestMethod != http.MethodGet) {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		switch {
+		case api == createNetworkAPI:
+			yes, failedPolicy := containerpolicy.ComplyTheNetworkPolicy(reqBody, identity.Resolve(keyHash))
+			if !yes {
+				msg := fmt.Sprintf("Network Body does not comply with the network policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+			if !containerpolicy.ComplyTheOwnerLabel(containerpolicy.LabelsFromBody(reqBody), identity.Resolve(keyHash)) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+			}
+
+			// the network takes the options of the config-only network, the config without the owner is only for admin
+			if configFrom := containerpolicy.DecodeNetworkBody(reqBody).ConfigFrom.Network; configFrom != "" {
+				keyHashFromMapa, found := NetworkOwner(configFrom)
+				if !found || !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: " + configFrom}
+				}
+				config, err := InspectNetwork(configFrom)
+				if err != nil {
+					log.Println("[InspectNetwork] Error occurred:", err)
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't inspect the network: " + configFrom}
+				}
+				yes, failedPolicy = containerpolicy.ComplyTheNetworkConfigFrom(reqBody, config, identity.Resolve(keyHash))
+				if !yes {
+					msg := fmt.Sprintf("Network Body does not comply with the network policy: %s", failedPolicy)
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+				}
+			}
+		case networkActionRegex.MatchString(api):
+			// the networks of docker daemon and the networks without the owner can be used by everyone
+			network := networkActionRegex.FindStringSubmatch(api)[1]
+			keyHashFromMapa, found := NetworkOwner(network)
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: " + network}
+			}
+
+			err := CheckDatabaseAndMakeMapa()
+			if err != nil {
+				log.Println("[CheckDatabaseAndMakeMapa] Error occurred:", err)
+			}
+			containerID := DefineContainerID(actionWithContainerAPI + containerpolicy.DecodeNetworkBody(reqBody).Container)
+			keyHashFromMapa, found = IDAndHashKeyMapping[containerID]
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your container"}
+			}
+		case req.RequestMethod == http.MethodDelete:
+			// the network without the owner is only for admin
+			network := strings.TrimPrefix(api, networksAPI+"/")
+			keyHashFromMapa, _ := NetworkOwner(network)
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: " + network}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// the service without the owner is only for admin
+	if strings.HasPrefix(api, servicesAPI+"/") && req.RequestMethod == http.MethodDelete {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		service := strings.TrimPrefix(api, servicesAPI+"/")
+		keyHashFromMapa, _ := SwarmObjectOwner(ServiceAndHashKeyMapping, service)
+		if allow := AllowMakeTheAction(keyHashFromMapa, CalculateHash(key)); !allow {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your service: " + service}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker service ls, inspect and logs are allowed for everyone, create, update and remove are judged here
+	if api == createServiceAPI || updateServiceRegex.MatchString(api) {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		if matches := updateServiceRegex.FindStringSubmatch(api); matches != nil {
+			keyHashFromMapa, _ := SwarmObjectOwner(ServiceAndHashKeyMapping, matches[1])
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your service: " + matches[1]}
+			}
+		}
+
+		// the tasks of the service are the containers, so the container policy judges ContainerSpec
+		caller := identity.Resolve(keyHash)
+		containerBody, err := containerpolicy.ContainerBodyFromService(reqBody)
+		if err != nil {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't judge the service: " + err.Error()}
+		}
+		yes, failedPolicy := containerpolicy.ComplyTheContainerPolicy(containerBody, caller)
+		if !yes {
+			msg := fmt.Sprintf("Service ContainerSpec does not comply with the container policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+
+		// docker daemon doesn't create the service without the image
+		scope := containerpolicy.ScopeFromBody(containerBody, caller)
+		if scope.Image != "" {
+			yes, failedPolicy = containerpolicy.ComplyTheImagePolicy(scope.Image, scope)
+			if !yes {
+				msg := fmt.Sprintf("Service Image does not comply with the image policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+		// docker CLI pins the image of the service by the digest
+		if digest := containerpolicy.DigestFromImage(scope.Image); digest != "" {
+			yes, failedPolicy = containerpolicy.ComplyTheDigestDenyList([]string{digest})
+			if !yes {
+				msg := fmt.Sprintf("Service Image is on the digest deny-list: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+
+		yes, failedPolicy = containerpolicy.ComplyTheVolumeMounts(containerBody, caller)
+		if !yes {
+			msg := fmt.Sprintf("Service Mounts do not comply with the volume policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+		for _, network := range containerpolicy.NetworksOfContainer(containerBody) {
+			keyHashFromMapa, found := NetworkOwner(network)
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: " + network}
+			}
+		}
+		for _, name := range containerpolicy.NamedVolumes(containerBody) {
+			keyHashFromMapa, found := VolumeAndHashKeyMapping[name]
+			if found && !AllowMakeTheAction(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: " + name}
+			}
+		}
+
+		if !containerpolicy.ComplyTheOwnerLabel(containerpolicy.LabelsFromBody(containerBody), caller) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+		}
+		yes, failedPolicy = containerpolicy.ComplyTheLabelPolicy(containerBody, caller)
+		if !yes {
+			msg := fmt.Sprintf("Service Labels do not comply with the container policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+
+		// the secret or the config without the owner is only for admin
+		secrets, configs := containerpolicy.SecretsAndConfigsOfService(reqBody)
+		for _, secret := range secrets {
+			keyHashFromMapa, _ := SwarmObjectOwner(SecretAndHashKeyMapping, secret)
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your secret: " + secret}
+			}
+		}
+		for _, config := range configs {
+			keyHashFromMapa, _ := SwarmObjectOwner(ConfigAndHashKeyMapping, config)
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your config: " + config}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker secret ls and docker config ls are allowed for everyone
+	if strings.HasPrefix(api, secretsAPI+"/") || strings.HasPrefix(api, configsAPI+"/") {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		mapa, kind, prefix := SecretAndHashKeyMapping, "secret", secretsAPI+"/"
+		if strings.HasPrefix(api, configsAPI+"/") {
+			mapa, kind, prefix = ConfigAndHashKeyMapping, "config", configsAPI+"/"
+		}
+		// create of the existing name fails at docker daemon, so only inspect, update and remove are checked
+		if api != prefix+"create" {
+			nameOrID := strings.TrimSuffix(strings.TrimPrefix(api, prefix), "/update")
+			keyHashFromMapa, _ := SwarmObjectOwner(mapa, nameOrID)
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your " + kind + ": " + nameOrID}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	// docker commit is allowed for the owner of the container into the namespace of the owner
+	if api == commitAPI {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		// changes can have "&" inside, so we parse the query docker daemon gets
+		commitURL, err := url.ParseRequestURI(req.RequestURI)
+		if err != nil {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't parse the query of the commit"}
+		}
+		query := commitURL.Query()
+
+		err = CheckDatabaseAndMakeMapa()
+		if err != nil {
+			log.Println("[CheckDatabaseAndMakeMapa] Error occurred:", err)
+		}
+		// the container without the owner is only for admin
+		containerID := DefineContainerID(actionWithContainerAPI + query.Get("container"))
+		if allow := AllowMakeTheAction(IDAndHashKeyMapping[containerID], keyHash); !allow || query.Get("container") == "" {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your container"}
+		}
+
+		caller := identity.Resolve(keyHash)
+		repository := query.Get("repo")
+		if !containerpolicy.ComplyTheCommitRepository(repository, caller) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The repository of the commit must be inside of your namespace: " + repository}
+		}
+		if repository != "" {
+			image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(repository, query.Get("tag")))
+			if keyHashFromMapa, found := ImageAndHashKeyMapping[image]; found && !AllowManageTheImage(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: " + image}
+			}
+		}
+
+		yes, failedPolicy := containerpolicy.ComplyTheCommitChanges(reqBody, query["changes"], caller)
+		if !yes {
+			msg := fmt.Sprintf("Commit changes do not comply with the image config policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	if api == buildImageAPI {
+		keyHash := ""
+		if req.RequestHeaders[headerWithToken] != "" {
+			keyHash = CalculateHash(req.RequestHeaders[headerWithToken])
+			if yes := IsItAdmin(keyHash); yes {
+				return authorization.Response{Allow: true}
+			}
+		}
+
+		// buildargs and remote can have "&" inside, so we parse the query docker daemon gets
+		buildURL, err := url.ParseRequestURI(req.RequestURI)
+		if err != nil {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't parse the query of the build"}
+		}
+		yes, failedPolicy := containerpolicy.ComplyTheBuildPolicy(buildURL.Query(), identity.Resolve(keyHash))
+		if !yes {
+			msg := fmt.Sprintf("Build does not comply with the build policy: %s", failedPolicy)
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+		}
+
+		if !containerpolicy.ComplyTheOwnerLabel(containerpolicy.LabelsFromBuild(buildURL.Query()), identity.Resolve(keyHash)) {
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. The label " + containerpolicy.OwnerLabel + " must be yours"}
+		}
+
+		// the tags of other's images can't be moved to the new image
+		for _, tag := range buildURL.Query()["t"] {
+			keyHashFromMapa, found := ImageAndHashKeyMapping[containerpolicy.NormalizeImage(tag)]
+			if found && !AllowManageTheImage(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: " + tag}
+			}
+		}
+		return authorization.Response{Allow: true}
+	}
+
+	if strings.HasPrefix(api, actionWithImageAPI) && (req.RequestMethod == http.MethodDelete || imageActionRegex.MatchString(api)) {
+		key, found := req.RequestHeaders[headerWithToken]
+		if !found {
+			instruction := fmt.Sprintf("Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - %s", manual)
+			return authorization.Response{Allow: false, Msg: instruction}
+		}
+		keyHash := CalculateHash(key)
+		if yes := IsItAdmin(keyHash); yes {
+			return authorization.Response{Allow: true}
+		}
+
+		if req.RequestMethod == http.MethodDelete {
+			keyHashFromMapa, _ := ImageOwner(strings.TrimPrefix(api, actionWithImageAPI))
+			if allow := AllowManageTheImage(keyHashFromMapa, keyHash); !allow {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image"}
+			}
+			return authorization.Response{Allow: true}
+		}
+
+		matches := imageActionRegex.FindStringSubmatch(api)
+		repository, action := matches[1], matches[2]
+		switch action {
+		case "push":
+			images := ImagesOfThePush(repository, query.Get("tag"))
+			if len(images) == 0 {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image"}
+			}
+			for _, image := range images {
+				if allow := AllowManageTheImage(ImageAndHashKeyMapping[image], keyHash); !allow {
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image"}
+				}
+			}
+			yes, failedPolicy := containerpolicy.ComplyThePushPolicy(repository, identity.Resolve(keyHash))
+			if !yes {
+				msg := fmt.Sprintf("Image %s does not comply with the push policy: %s", repository, failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		case "tag":
+			// other's image can't get the new name, it could be pushed then
+			if keyHashFromMapa, found := ImageOwner(repository); found && !AllowManageTheImage(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: " + repository}
+			}
+			// the image from the denied source can't get the name of the allowed one
+			sources, err := ImageNames(repository)
+			if err != nil {
+				log.Println("[ImageNames] Error occurred:", err)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't judge the image: " + repository}
+			}
+			for _, source := range sources {
+				yes, failedPolicy := containerpolicy.ComplyTheImagePolicy(source, containerpolicy.Scope{Caller: identity.Resolve(keyHash), Image: source})
+				if !yes {
+					msg := fmt.Sprintf("Image %s does not comply with the image policy: %s", source, failedPolicy)
+					return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+				}
+			}
+			// the tag of other's image can't be moved to another image
+			image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(query.Get("repo"), query.Get("tag")))
+			if keyHashFromMapa, found := ImageAndHashKeyMapping[image]; found && !AllowManageTheImage(keyHashFromMapa, keyHash) {
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your image"}
+			}
+		}
+		return authorization.Response{Allow: true}
 	}
 
 	if strings.HasPrefix(obj, actionWithContainerAPI) {
@@ -247,29 +1176,82 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 		}
 		keyHash := CalculateHash(key)
 
+		renameRegex := regexp.MustCompile(`^/containers/[^/]+/rename$`)
+		if renameRegex.MatchString(api) {
+			caller := identity.Resolve(keyHash)
+			yes, failedPolicy := containerpolicy.ComplyTheNamingPolicy(query.Get("name"), caller)
+			if !yes {
+				msg := fmt.Sprintf("Container Name does not comply with the container policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+
 		err := CheckDatabaseAndMakeMapa()
 		if err != nil {
 			errorMsg := fmt.Sprintf("[CheckDatabaseAndMakeMapa] Error occurred: %e", err)
 			log.Println(errorMsg)
 		}
 
+		// the name docker daemon doesn't know or the ID of nobody
 		containerID := DefineContainerID(obj)
 		if containerID == trash {
-			return authorization.Response{Allow: true}
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't find the container"}
+		}
+
+		execRegex := regexp.MustCompile(`^/containers/[^/]+/exec$`)
+		if execRegex.MatchString(api) {
+			caller := identity.Resolve(keyHash)
+			image := ContainerImage(containerID)
+			yes, failedPolicy := containerpolicy.ComplyTheExecPolicy(reqBody, caller, image)
+			if !yes {
+				msg := fmt.Sprintf("Exec Body does not comply with the exec policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+
+			yes, failedPolicy = containerpolicy.ComplyTheExecCommand(reqBody, caller, image)
+			if !yes {
+				msg := fmt.Sprintf("Exec Cmd does not comply with the container policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
 		}
 
 		keyHashFromMapa, found := IDAndHashKeyMapping[containerID]
 		if found {
-			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); allow {
-				return authorization.Response{Allow: true}
-			} else {
+			if allow := AllowMakeTheAction(keyHashFromMapa, keyHash); !allow {
 				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. That's not your container"}
 			}
 		} else {
 			log.Println("That's container was created right now:", containerID)
 			IDAndHashKeyMapping[containerID] = keyHash
-			return authorization.Response{Allow: true}
 		}
+
+		// Only the owner gets here, so we don't tell others about the limits of the container
+		updateRegex := regexp.MustCompile(`^/containers/[^/]+/update$`)
+		if updateRegex.MatchString(api) {
+			caller := identity.Resolve(keyHash)
+			var current *container.HostConfig
+			image := ""
+			if inspect, err := InspectContainer(containerID); err == nil {
+				current = inspect.HostConfig
+				image = inspect.Config.Image
+			}
+			yes, failedPolicy := containerpolicy.ComplyTheUpdatePolicy(reqBody, current, caller, image)
+			if !yes {
+				msg := fmt.Sprintf("Update Body does not comply with the update policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+
+		archiveRegex := regexp.MustCompile(`^/containers/[^/]+/archive$`)
+		if archiveRegex.MatchString(api) {
+			caller := identity.Resolve(keyHash)
+			yes, failedPolicy := containerpolicy.ComplyTheArchivePolicy(req.RequestMethod, query.Get("path"), caller, ContainerImage(containerID))
+			if !yes {
+				msg := fmt.Sprintf("Archive path does not comply with the archive policy: %s", failedPolicy)
+				return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin." + msg}
+			}
+		}
+		return authorization.Response{Allow: true}
 	}
 
 	if strings.HasPrefix(obj, execAtContainerAPI) {
@@ -281,9 +1263,10 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 		}
 
 		keyHash := CalculateHash(key)
+		// the name docker daemon doesn't know or the ID of nobody
 		containerID := DefineContainerID(obj)
 		if containerID == trash {
-			return authorization.Response{Allow: true}
+			return authorization.Response{Allow: false, Msg: "Access denied by AuthPlugin. Can't find the container"}
 		}
 
 		keyHashFromMapa, found := IDAndHashKeyMapping[containerID]
@@ -301,7 +1284,138 @@ func (plugin *CasbinAuthZPlugin) AuthZReq(req authorization.Request) authorizati
 
 // AuthZRes authorizes the docker client response.
 // All responses are allowed by default.
+// Here we know the action is done, so we record who owns the images
 func (plugin *CasbinAuthZPlugin) AuthZRes(req authorization.Request) authorization.Response {
+	reqURI, _ := url.QueryUnescape(req.RequestURI)
+	reqURL, _ := url.ParseRequestURI(reqURI)
+	if reqURL == nil || req.ResponseStatusCode >= http.StatusMultipleChoices {
+		return authorization.Response{Allow: true}
+	}
+
+	re := regexp.MustCompile(`/v\d+\.\d+/`)
+	api := re.ReplaceAllString(reqURL.Path, "/")
+	query := rawQuery(req.RequestURI)
+	keyHash := ""
+	if req.RequestHeaders[headerWithToken] != "" {
+		keyHash = CalculateHash(req.RequestHeaders[headerWithToken])
+	}
+
+	switch {
+	case api == pullImageAPI && query.Get("fromSrc") != "" && query.Get("repo") != "" && keyHash != "":
+		image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(query.Get("repo"), query.Get("tag")))
+		log.Println("That's image was imported right now:", image)
+		ImageAndHashKeyMapping[image] = keyHash
+		recordImageID(image, keyHash)
+	case api == pullImageAPI && query.Get("fromImage") != "":
+		image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(query.Get("fromImage"), query.Get("tag")))
+		// the first one who pulled the image owns it
+		if _, found := ImageAndHashKeyMapping[image]; !found && keyHash != "" {
+			log.Println("That's image was pulled right now:", image)
+			ImageAndHashKeyMapping[image] = keyHash
+			recordImageID(image, keyHash)
+		}
+	case imageActionRegex.MatchString(api) && imageActionRegex.FindStringSubmatch(api)[2] == "tag":
+		image := containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(query.Get("repo"), query.Get("tag")))
+		if keyHash != "" {
+			log.Println("That's image was tagged right now:", image)
+			ImageAndHashKeyMapping[image] = keyHash
+			recordImageID(image, keyHash)
+		}
+	case api == buildImageAPI && keyHash != "":
+		buildURL, err := url.ParseRequestURI(req.RequestURI)
+		if err != nil {
+			break
+		}
+		for _, tag := range buildURL.Query()["t"] {
+			image := containerpolicy.NormalizeImage(tag)
+			log.Println("That's image was built right now:", image)
+			ImageAndHashKeyMapping[image] = keyHash
+			recordImageID(image, keyHash)
+		}
+	case api == commitAPI && keyHash != "":
+		commitURL, err := url.ParseRequestURI(req.RequestURI)
+		if err != nil {
+			break
+		}
+		images := []string{containerpolicy.ImageIDFromCommit(string(req.ResponseBody))}
+		if repository := commitURL.Query().Get("repo"); repository != "" {
+			images = append(images, containerpolicy.NormalizeImage(containerpolicy.ImageFromPull(repository, commitURL.Query().Get("tag"))))
+		}
+		for _, image := range images {
+			if image != "" {
+				log.Println("That's image was committed right now:", image)
+				ImageAndHashKeyMapping[image] = keyHash
+			}
+		}
+	case api == createVolumeAPI && keyHash != "":
+		name := containerpolicy.VolumeNameFromBody(string(req.ResponseBody))
+		if _, found := VolumeAndHashKeyMapping[name]; !found && name != "" {
+			log.Println("That's volume was created right now:", name)
+			VolumeAndHashKeyMapping[name] = keyHash
+		}
+	case api == creationContainerAPI && keyHash != "":
+		// docker creates the named volumes of the container too
+		for _, name := range containerpolicy.NamedVolumes(string(req.RequestBody)) {
+			if _, found := VolumeAndHashKeyMapping[name]; !found {
+				log.Println("That's volume was created with the container:", name)
+				VolumeAndHashKeyMapping[name] = keyHash
+			}
+		}
+	case api == createNetworkAPI && keyHash != "":
+		// the name can be taken by the new network after the old one was removed
+		network := containerpolicy.DecodeNetworkBody(string(req.ResponseBody))
+		network.Name = containerpolicy.DecodeNetworkBody(string(req.RequestBody)).Name
+		log.Println("That's network was created right now:", network.Name, network.Id)
+		for _, nameOrID := range []string{network.Name, network.Id} {
+			if nameOrID != "" {
+				NetworkAndHashKeyMapping[nameOrID] = keyHash
+			}
+		}
+	case (api == secretsAPI+"/create" || api == configsAPI+"/create") && keyHash != "":
+		mapa := SecretAndHashKeyMapping
+		if api == configsAPI+"/create" {
+			mapa = ConfigAndHashKeyMapping
+		}
+		object := containerpolicy.DecodeSwarmObject(string(req.ResponseBody))
+		object.Name = containerpolicy.DecodeSwarmObject(string(req.RequestBody)).Name
+		log.Println("That's secret or config was created right now:", object.Name, object.ID)
+		for _, nameOrID := range []string{object.Name, object.ID} {
+			if nameOrID != "" {
+				mapa[nameOrID] = keyHash
+			}
+		}
+	case api == createServiceAPI && keyHash != "":
+		service := containerpolicy.DecodeSwarmObject(string(req.ResponseBody))
+		service.Name = containerpolicy.DecodeSwarmObject(string(req.RequestBody)).Name
+		log.Println("That's service was created right now:", service.Name, service.ID)
+		for _, nameOrID := range []string{service.Name, service.ID} {
+			if nameOrID != "" {
+				ServiceAndHashKeyMapping[nameOrID] = keyHash
+			}
+		}
+	case strings.HasPrefix(api, servicesAPI+"/") && req.RequestMethod == http.MethodDelete:
+		delete(ServiceAndHashKeyMapping, strings.TrimPrefix(api, servicesAPI+"/"))
+	case strings.HasPrefix(api, secretsAPI+"/") && req.RequestMethod == http.MethodDelete:
+		delete(SecretAndHashKeyMapping, strings.TrimPrefix(api, secretsAPI+"/"))
+	case strings.HasPrefix(api, configsAPI+"/") && req.RequestMethod == http.MethodDelete:
+		delete(ConfigAndHashKeyMapping, strings.TrimPrefix(api, configsAPI+"/"))
+	case strings.HasPrefix(api, networksAPI+"/") && req.RequestMethod == http.MethodDelete:
+		delete(NetworkAndHashKeyMapping, strings.TrimPrefix(api, networksAPI+"/"))
+	case strings.HasPrefix(api, volumesAPI+"/") && req.RequestMethod == http.MethodDelete:
+		delete(VolumeAndHashKeyMapping, strings.TrimPrefix(api, volumesAPI+"/"))
+	case strings.HasPrefix(api, actionWithImageAPI) && req.RequestMethod == http.MethodDelete:
+		// docker daemon tells every name and ID it removed, the new image can get them
+		delete(ImageAndHashKeyMapping, imageKey(strings.TrimPrefix(api, actionWithImageAPI)))
+		for _, image := range containerpolicy.ImagesOfDelete(string(req.ResponseBody)) {
+			delete(ImageAndHashKeyMapping, image)
+		}
+	case strings.HasPrefix(api, actionWithContainerAPI) && req.RequestMethod == http.MethodDelete:
+		// the name and the ID are forgotten together, the new container can get the name
+		containerID := DefineContainerID(api)
+		delete(IDAndHashKeyMapping, containerID)
+		delete(IDAndNameMapping, containerID)
+	}
+
 	// Allowed by default.
 	return authorization.Response{Allow: true}
 }
@@ -4,15 +4,24 @@ import (
 	"encoding/json"
 	fmt2 "fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
 
+	containerpolicy "github.com/casbin/casbin-authz-plugin/containerPolicy"
+	"github.com/casbin/casbin-authz-plugin/identity"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/docker/go-plugins-helpers/authorization"
 	"github.com/stretchr/testify/assert"
 )
 
 type AdmitTestCase struct {
 	name    string
-	body    map[string]string
+	body    map[string]interface{}
 	request authorization.Request
 	result  authorization.Response
 }
@@ -21,18 +30,392 @@ func TestAuthZReq(t *testing.T) {
 	authPlugin := &CasbinAuthZPlugin{}
 	testContainerID := "f760a15e19af19f97e52ead30d4cb5f8c906e601bab8cb63ccc071857df44b75"
 	testContainerNAME := "test_container"
+	containerpolicy.PathToThePolicy = "testdata/container_policy.csv"
+	containerpolicy.PathToTheExecPolicy = "testdata/exec_policy.csv"
+	containerpolicy.PathToTheImagePolicy = "testdata/image_policy.csv"
+	containerpolicy.PathToTheBuildPolicy = "testdata/build_policy.csv"
+	containerpolicy.PathToTheVolumePolicy = "testdata/volume_policy.csv"
+	containerpolicy.PathToTheNetworkPolicy = "testdata/network_policy.csv"
+	containerpolicy.PathToTheDigestDenyList = "testdata/digest_denylist.csv"
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+	ImageAndHashKeyMapping["registry.corp.internal/anna/api:1.0"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	ImageAndHashKeyMapping["registry.corp.internal/anna/api:latest"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	ImageAndHashKeyMapping["docker.io/roman/api:1.0"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	VolumeAndHashKeyMapping["anna-data"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	NetworkAndHashKeyMapping["anna-net"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	SecretAndHashKeyMapping["anna-token"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	SecretAndHashKeyMapping["ktnbjxoalbkvbvedmg1urrz8h"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	NetworkAndHashKeyMapping["3c1e2b9f4a8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a291807f6e5d4c3b2a19080"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	// docker daemon knows the container of User1 and none of the images
+	stop := startFakeDaemon(fakeDaemon{
+		containers: map[string]types.ContainerJSON{
+			testContainerID: {
+				ContainerJSONBase: &types.ContainerJSONBase{ID: testContainerID, Name: "/" + testContainerNAME, HostConfig: &container.HostConfig{}},
+				Config:            &container.Config{Image: "alpine"},
+			},
+		},
+		failures: map[string]int{"/images/busybox/json": http.StatusInternalServerError},
+	})
+	defer stop()
+	ImageAndHashKeyMapping["sha256:0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	ImageAndHashKeyMapping["sha256:7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	IDAndHashKeyMapping["a4b5c6d7e8f9"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	IDAndHashKeyMapping["b7c8d9e0f1a2"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	ServiceAndHashKeyMapping["k1xz6ztn1c6v2qdnwugpyvbyu"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	ServiceAndHashKeyMapping["anna-api"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
 
 	testCases := []AdmitTestCase{
 		{
 			name: "Test creation a volumes",
-			body: map[string]string{"Driver": "local"},
+			body: map[string]interface{}{"Driver": "local"},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/volumes/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test creation a volume with the bind of the root",
+			body: map[string]interface{}{"Name": "root", "DriverOpts": map[string]string{"type": "none", "o": "bind", "device": "/"}},
 			request: authorization.Request{
 				RequestURI:     "/v1.42/volumes/create",
 				RequestMethod:  "POST",
 				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
 			},
 			result: authorization.Response{
-				Allow: false, Msg: "Access denied by AuthPlugin: /volumes/create", Err: ""},
+				Allow: false, Msg: "Access denied by AuthPlugin.Volume Body does not comply with the volume policy: driveropts.o", Err: ""},
+		},
+		{
+			name: "Test removing other's volume",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/volumes/anna-data",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: anna-data", Err: ""},
+		},
+		{
+			name: "Test mounting other's volume",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"Binds": []string{"anna-data:/data"}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your volume: anna-data", Err: ""},
+		},
+		{
+			name: "Test prune of volumes",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/volumes/prune",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune volumes", Err: ""},
+		},
+		{
+			name: "Test prune of own volumes",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/volumes/prune?filters=%7B%22label%22%3A%7B%22authz.owner%3Droman%22%3Atrue%7D%7D",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune volumes", Err: ""},
+		},
+		{
+			name: "Test prune of own containers",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/prune?filters=%7B%22label%22%3A%7B%22authz.owner%3Droman%22%3Atrue%7D%7D",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test prune of other's images",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/images/prune?filters=%7B%22label%22%3A%7B%22authz.owner%3Danna%22%3Atrue%7D%7D",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune without the filter of the owner, use --filter label=authz.owner=roman", Err: ""},
+		},
+		{
+			name: "Test prune of the build cache",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/build/prune?all=1",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune the build cache", Err: ""},
+		},
+		{
+			name: "Test creation a volume marked as other's",
+			body: map[string]interface{}{"Name": "roman-cache", "Labels": map[string]string{"authz.owner": "anna"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/volumes/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. The label authz.owner must be yours", Err: ""},
+		},
+		{
+			name: "Test creation a network with the forbidden driver",
+			body: map[string]interface{}{"Name": "roman-net", "Driver": "ipvlan"},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Network Body does not comply with the network policy: driver", Err: ""},
+		},
+		{
+			name: "Test creation a network",
+			body: map[string]interface{}{"Name": "roman-net", "Driver": "bridge"},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test removing other's network by the prefix of ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/3c1e2b9f4a8d",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: 3c1e2b9f4a8d", Err: ""},
+		},
+		{
+			name: "Test connecting to other's network",
+			body: map[string]interface{}{"Container": "roman-db"},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/anna-net/connect",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: anna-net", Err: ""},
+		},
+		{
+			name: "Test joining other's network at the creation",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"NetworkMode": "anna-net"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: anna-net", Err: ""},
+		},
+		{
+			name: "Test joining the network of other's container at the creation",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"NetworkMode": "container:a4b5c6d7e8f9"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your container: a4b5c6d7e8f9", Err: ""},
+		},
+		{
+			name: "Test joining the processes of the container docker daemon doesn't know",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"PidMode": "container:ghost"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your container: ghost", Err: ""},
+		},
+		{
+			name: "Test joining the network of own container at the creation",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"NetworkMode": "container:b7c8d9e0f1a2"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test mounting the volumes of other's container at the creation",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"VolumesFrom": []string{"b7c8d9e0f1a2", "a4b5c6d7e8f9:ro"}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your container: a4b5c6d7e8f9", Err: ""},
+		},
+		{
+			name: "Test mounting the volumes of own container at the creation",
+			body: map[string]interface{}{"Image": "alpine", "HostConfig": map[string]interface{}{"VolumesFrom": []string{"b7c8d9e0f1a2:rw"}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/create?name=roman-db",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test prune of networks",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/prune",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Only admin can prune without the filter of the owner, use --filter label=authz.owner=roman", Err: ""},
+		},
+		{
+			name: "Test creation a service with nsenter",
+			body: map[string]interface{}{"Name": "roman-web", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "alpine", "Command": []string{"nsenter"}, "Args": []string{"-t", "1", "sh"}}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Service ContainerSpec does not comply with the container policy: command", Err: ""},
+		},
+		{
+			name: "Test update of a service with the image from other registry",
+			body: map[string]interface{}{"Name": "roman-web", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "quay.io/roman/web:1.0"}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/k1xz6ztn1c6v2qdnwugpyvbyu/update?version=12",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Service Image does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "Test creation a service with the bind mount of the root",
+			body: map[string]interface{}{"Name": "roman-web", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "alpine",
+				"Mounts": []map[string]string{{"Type": "bind", "Source": "/", "Target": "/host"}}}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Service ContainerSpec does not comply with the container policy: binds", Err: ""},
+		},
+		{
+			name: "Test update of other's service",
+			body: map[string]interface{}{"Name": "anna-api", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "alpine"}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/anna-api/update?version=3",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your service: anna-api", Err: ""},
+		},
+		{
+			name: "Test removing other's service",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/anna-api",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your service: anna-api", Err: ""},
+		},
+		{
+			name: "Test removing the service without the owner",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/legacy-web",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your service: legacy-web", Err: ""},
+		},
+		{
+			name: "Test creation a service with other's secret",
+			body: map[string]interface{}{"Name": "roman-web", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "alpine",
+				"Secrets": []map[string]string{{"SecretID": "ktnbjxoalbkvbvedmg1urrz8h", "SecretName": "roman-token"}}}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your secret: ktnbjxoalbkvbvedmg1urrz8h", Err: ""},
+		},
+		{
+			name: "Test creation a service",
+			body: map[string]interface{}{"Name": "roman-web", "TaskTemplate": map[string]interface{}{"ContainerSpec": map[string]interface{}{"Image": "alpine", "Args": []string{"sleep", "infinity"}}}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/services/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test inspect of other's secret by the prefix of ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/secrets/ktnbjx",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your secret: ktnbjx", Err: ""},
+		},
+		{
+			name: "Test removing the config without the owner",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/configs/nginx.conf",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your config: nginx.conf", Err: ""},
+		},
+		{
+			name: "Test creation a secret",
+			body: map[string]interface{}{"Name": "roman-token", "Data": "c2VjcmV0"},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/secrets/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test inspect of swarm",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/swarm",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin: /swarm", Err: ""},
 		},
 		{
 			name: "Test commit a container",
@@ -42,7 +425,7 @@ func TestAuthZReq(t *testing.T) {
 				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
 			},
 			result: authorization.Response{
-				Allow: false, Msg: "Access denied by AuthPlugin: /commit?author=&comment=&container=5hae354g3&repo=&tag=", Err: ""},
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your container", Err: ""},
 		},
 		{
 			name: "Test actions with a plugin",
@@ -124,20 +507,1166 @@ func TestAuthZReq(t *testing.T) {
 			result: authorization.Response{
 				Allow: false, Msg: "Access denied by AuthPlugin. You can't exec other people's containers", Err: ""},
 		},
-	}
-
-	for _, testCase := range testCases {
-		t.Run(testCase.name, func(t *testing.T) {
-
-			data, err := json.Marshal(testCase.body)
-			if err != nil {
-				errorMsg := fmt2.Sprintf("Can't Marshal data %e", err)
-				log.Println(errorMsg)
-			}
-
-			testCase.request.RequestBody = data
-			resp := authPlugin.AuthZReq(testCase.request)
-			assert.Equal(t, testCase.result, resp)
+		{
+			name: "User1 want to create a container with other's name",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/create?name=anna-web",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Container Name does not comply with the container policy: name", Err: ""},
+		},
+		{
+			name: "User1 want to rename a container to other's name",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/" + testContainerID + "/rename?name=anna-web",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Container Name does not comply with the container policy: name", Err: ""},
+		},
+		{
+			name: "User1 want to hide other's name of the container inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/create?x=%26name%3Droman-web&name=anna-web",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Container Name does not comply with the container policy: name", Err: ""},
+		},
+		{
+			name: "User1 want to hide the rename to other's name inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/" + testContainerID + "/rename?x=%26name%3Droman-web&name=anna-web",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Container Name does not comply with the container policy: name", Err: ""},
+		},
+		{
+			name: "User1 want to exec nsenter at his own container",
+			body: map[string]interface{}{"Cmd": []string{"nsenter", "-t", "1", "-m", "sh"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/" + testContainerID + "/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Exec Cmd does not comply with the container policy: command", Err: ""},
+		},
+		{
+			name: "User1 want to exec his own container as privileged",
+			body: map[string]interface{}{"Cmd": []string{"sh"}, "Privileged": true, "User": "0"},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/" + testContainerID + "/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Exec Body does not comply with the exec policy: privileged", Err: ""},
+		},
+		{
+			name: "User1 want to pull from unknown registry",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromImage=evil.example.com%2Fminer&tag=latest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image evil.example.com/miner:latest does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "User1 want to hide the pull from unknown registry inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?x=%26fromImage%3Dalpine&fromImage=evil.example.com%2Fminer&tag=latest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image evil.example.com/miner:latest does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "User1 want to pull the flagged digest",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromImage=alpine&tag=sha256%3A2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image alpine@sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 is on the digest deny-list: sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 (CVE-2024-3094)", Err: ""},
+		},
+		{
+			name: "User1 want to run the image docker daemon fails to inspect",
+			body: map[string]interface{}{"Image": "busybox"},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Can't inspect the image: busybox", Err: ""},
+		},
+		{
+			name: "User1 want to run the cached image of unknown registry",
+			body: map[string]interface{}{"Image": "evil.example.com/miner"},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/create?name=roman-miner",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Container Image does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "User1 want to delete other's image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/registry.corp.internal/anna/api:1.0",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to delete the image without the owner",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/alpine",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to push all tags of other's image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/registry.corp.internal/anna/api/push",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to push his own image outside of his namespace",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/roman/api/push?tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image roman/api does not comply with the push policy: push", Err: ""},
+		},
+		{
+			name: "User1 want to move the tag of other's image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/roman/api:1.0/tag?repo=registry.corp.internal%2Fanna%2Fapi&tag=latest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to hide the tag of other's image inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/roman/api:1.0/tag?x=%26repo%3Droman%2Fapi&repo=registry.corp.internal%2Fanna%2Fapi&tag=latest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to tag his own image into his namespace",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/roman/api:1.0/tag?repo=registry.corp.internal%2Froman%2Fapi&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "User1 want to tag other's image into his namespace",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/registry.corp.internal/anna/api:1.0/tag?repo=roman%2Fapi&tag=copy",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: registry.corp.internal/anna/api:1.0", Err: ""},
+		},
+		{
+			name: "User1 want to tag other's image by the prefix of the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/0c1d2e3f4a5b/tag?repo=roman%2Fapi&tag=copy",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: 0c1d2e3f4a5b", Err: ""},
+		},
+		{
+			name: "User1 want to remove other's image by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/sha256:0c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d?force=1",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image", Err: ""},
+		},
+		{
+			name: "User1 want to remove his own image by the prefix of the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/7e8f9a0b1c2d",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "User1 want to tag the image from unknown registry",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/evil.example.com/miner:latest/tag?repo=roman%2Fminer&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image evil.example.com/miner:latest does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "User1 want to tag the image docker daemon doesn't know by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/5f4e3d2c1b0a/tag?repo=roman%2Fminer&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Can't judge the image: 5f4e3d2c1b0a", Err: ""},
+		},
+		{
+			name: "User1 want to import the image as the image of unknown registry",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromSrc=-&repo=evil.example.com%2Fminer&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image evil.example.com/miner:1.0 does not comply with the image policy: registry", Err: ""},
+		},
+		{
+			name: "User1 want to import the image as other's image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromSrc=-&repo=registry.corp.internal%2Fanna%2Fapi&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: registry.corp.internal/anna/api:1.0", Err: ""},
+		},
+		{
+			name: "User1 want to hide the import as other's image inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromSrc=-&x=%26repo%3Dregistry.corp.internal%2Froman%2Fbackup&repo=registry.corp.internal%2Fanna%2Fapi&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: registry.corp.internal/anna/api:1.0", Err: ""},
+		},
+		{
+			name: "User1 want to import the image into his namespace",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/create?fromSrc=-&repo=registry.corp.internal%2Froman%2Fbackup&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "User1 want to build with host network",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/build?buildargs=%7B%7D&networkmode=host&t=roman%2Fapi%3A2.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Build does not comply with the build policy: networkmode", Err: ""},
+		},
+		{
+			name: "User1 want to build other's image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/build?buildargs=%7B%7D&networkmode=default&t=registry.corp.internal%2Fanna%2Fapi%3A1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/x-tar"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: registry.corp.internal/anna/api:1.0", Err: ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+
+			data, err := json.Marshal(testCase.body)
+			if err != nil {
+				errorMsg := fmt2.Sprintf("Can't Marshal data %e", err)
+				log.Println(errorMsg)
+			}
+
+			testCase.request.RequestBody = data
+			resp := authPlugin.AuthZReq(testCase.request)
+			assert.Equal(t, testCase.result, resp)
+		})
+	}
+}
+
+func TestOwnerFilterOnList(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+	DefineOwnerFilterOnList(true)
+	defer DefineOwnerFilterOnList(false)
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test list of all containers",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/json?all=1",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. You can list only your containers, use docker ps --filter label=authz.owner=roman", Err: ""},
+		},
+		{
+			name: "Test list of other's containers",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/json?filters=%7B%22label%22%3A%7B%22authz.owner%3Danna%22%3Atrue%7D%7D",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. You can list only your containers, use docker ps --filter label=authz.owner=roman", Err: ""},
+		},
+		{
+			name: "Test list of own containers",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/json?all=1&filters=%7B%22label%22%3A%7B%22authz.owner%3Droman%22%3Atrue%7D%7D",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test list without AuthHeader",
+			request: authorization.Request{
+				RequestURI:    "/v1.42/containers/json?all=1",
+				RequestMethod: "GET",
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. AuthHeader is Empty. Follow the instruction - " + manual, Err: ""},
+		},
+		{
+			name: "Test list by the plugin itself",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/json?all=1",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": pluginToken},
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test the token of the plugin can only read",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/containers/anna-web/stop",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": pluginToken},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Can't find the container", Err: ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+}
+
+func TestPluginManagement(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	containerpolicy.PathToThePluginPolicy = "testdata/plugin_policy.csv"
+	DefineAdminToken("f3ab0df5dfdb79a088d1cb3c7c6c3658a222ac0d1b6d59ee031b62460c92e9b8")
+	defer DefineAdminToken("")
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test admin disables the authorization plugin",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/container-authz-plugin:latest/disable?force=1",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Nobody can switch off the authorization plugin: container-authz-plugin:latest", Err: ""},
+		},
+		{
+			name: "Test admin removes the authorization plugin",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/corp/container-authz-plugin?force=1",
+				RequestMethod:  "DELETE",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Nobody can switch off the authorization plugin: corp/container-authz-plugin", Err: ""},
+		},
+		{
+			name: "Test admin installs sshfs",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/pull?name=sshfs&remote=vieux%2Fsshfs%3Alatest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+				RequestBody:    []byte(`[{"Name":"network","Value":["host"]},{"Name":"capabilities","Value":["CAP_SYS_ADMIN"]}]`),
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test admin installs the plugin with all devices",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/pull?name=storage&remote=registry.corp.internal%2Fplugins%2Fstorage%3A1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+				RequestBody:    []byte(`[{"Name":"allow-all-devices","Value":["true"]}]`),
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Plugin registry.corp.internal/plugins/storage:1.0 does not comply with the plugin policy: allow-all-devices", Err: ""},
+		},
+		{
+			name: "Test admin installs the plugin of unknown registry hidden inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/pull?name=miner&x=%26remote%3Dvieux%2Fsshfs%3Alatest&remote=evil.example.com%2Fminer%3Alatest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+				RequestBody:    []byte(`[]`),
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Plugin evil.example.com/miner:latest does not comply with the plugin policy: remote", Err: ""},
+		},
+		{
+			name: "Test admin upgrades sshfs from docker hub",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/sshfs/upgrade?remote=weaveworks%2Fnet-plugin%3A2.5.2",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+				RequestBody:    []byte(`[]`),
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Plugin weaveworks/net-plugin:2.5.2 does not comply with the plugin policy: remote", Err: ""},
+		},
+		{
+			name: "Test admin creates the plugin from the tarball",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/create?name=local",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. The plugin from the tarball can't be judged by the plugin policy", Err: ""},
+		},
+		{
+			name: "Test user installs sshfs",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/pull?name=sshfs&remote=vieux%2Fsshfs%3Alatest",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+				RequestBody:    []byte(`[]`),
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin: /plugins/pull?name=sshfs&remote=vieux/sshfs:latest", Err: ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+}
+
+func TestCommit(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	containerpolicy.PathToTheImageConfigPolicy = "testdata/image_config_policy.csv"
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+	romanContainerID := "9d3c6b1a2f4e5d6c7b8a9f0e1d2c3b4a5f6e7d8c9b0a1f2e3d4c5b6a7f8e9d0c"
+	IDAndHashKeyMapping[romanContainerID[:12]] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	ImageAndHashKeyMapping["docker.io/infra/api:snapshot"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test commit of own container",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/commit?author=&comment=&container=" + romanContainerID + "&repo=roman%2Fapi&tag=snapshot",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+				RequestBody:    []byte("null"),
+			},
+			result: authorization.Response{
+				Allow: true, Msg: "", Err: ""},
+		},
+		{
+			name: "Test commit of own container into other's namespace",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/commit?container=" + romanContainerID + "&repo=anna%2Fapi&tag=snapshot",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. The repository of the commit must be inside of your namespace: anna/api", Err: ""},
+		},
+		{
+			name: "Test commit over the image of the teammate",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/commit?container=" + romanContainerID + "&repo=infra%2Fapi&tag=snapshot",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your image: docker.io/infra/api:snapshot", Err: ""},
+		},
+		{
+			name: "Test commit with USER root",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/commit?changes=USER+root&container=" + romanContainerID + "&repo=roman%2Fapi&tag=root",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Commit changes do not comply with the image config policy: user", Err: ""},
+		},
+		{
+			name: "Test commit of other's container",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/commit?container=" + romanContainerID[:12] + "&repo=anna%2Fapi",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "e51cc6373acd45d624e930cb8162cbcc", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your container", Err: ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.42/commit?container=" + romanContainerID + "&repo=roman%2Fapi&tag=snapshot",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseBody:       []byte(`{"Id":"sha256:5c1e9d8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d"}`),
+		ResponseStatusCode: 201,
+	})
+	assert.Equal(t, IDAndHashKeyMapping[romanContainerID[:12]], ImageAndHashKeyMapping["docker.io/roman/api:snapshot"])
+	assert.Equal(t, IDAndHashKeyMapping[romanContainerID[:12]], ImageAndHashKeyMapping["sha256:5c1e9d8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d"])
+}
+
+func TestAuthZRes(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	roman := "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/create?fromImage=registry.corp.internal%2Froman%2Fapi&tag=2.0",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	assert.Equal(t, roman, ImageAndHashKeyMapping["registry.corp.internal/roman/api:2.0"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/registry.corp.internal/roman/api:2.0/tag?repo=registry.corp.internal%2Froman%2Fapi&tag=stable",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 201,
+	})
+	assert.Equal(t, roman, ImageAndHashKeyMapping["registry.corp.internal/roman/api:stable"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/build?t=registry.corp.internal%2Froman%2Fapi%3A2.1&t=roman%2Fapi",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	assert.Equal(t, roman, ImageAndHashKeyMapping["registry.corp.internal/roman/api:2.1"])
+	assert.Equal(t, roman, ImageAndHashKeyMapping["docker.io/roman/api:latest"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/create?fromImage=registry.corp.internal%2Froman%2Fapi&tag=3.0",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 404,
+	})
+	_, found := ImageAndHashKeyMapping["registry.corp.internal/roman/api:3.0"]
+	assert.False(t, found)
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/create?x=%26fromImage%3Dalpine&fromImage=registry.corp.internal%2Froman%2Fapi&tag=5.0",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	assert.Equal(t, roman, ImageAndHashKeyMapping["registry.corp.internal/roman/api:5.0"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/registry.corp.internal/roman/api:stable",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	_, found = ImageAndHashKeyMapping["registry.corp.internal/roman/api:stable"]
+	assert.False(t, found)
+
+	// the other tags and the ID are removed together with the image
+	imageID := "sha256:4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e"
+	for _, image := range []string{"registry.corp.internal/roman/api:6.0", "registry.corp.internal/roman/api:6.1", imageID} {
+		ImageAndHashKeyMapping[image] = roman
+	}
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/registry.corp.internal/roman/api:6.0?force=1",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseBody:       []byte(`[{"Untagged":"registry.corp.internal/roman/api:6.0"},{"Untagged":"registry.corp.internal/roman/api:6.1"},{"Deleted":"` + imageID + `"}]`),
+		ResponseStatusCode: 200,
+	})
+	for _, image := range []string{"registry.corp.internal/roman/api:6.0", "registry.corp.internal/roman/api:6.1", imageID} {
+		_, found = ImageAndHashKeyMapping[image]
+		assert.False(t, found, image)
+	}
+
+	// the name of the image removed by the ID is forgotten too
+	ImageAndHashKeyMapping["docker.io/roman/tool:latest"] = roman
+	ImageAndHashKeyMapping[imageID] = roman
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/4d5e6f7a8b9c",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseBody:       []byte(`[{"Untagged":"roman/tool:latest"},{"Deleted":"` + imageID + `"}]`),
+		ResponseStatusCode: 200,
+	})
+	_, found = ImageAndHashKeyMapping["docker.io/roman/tool:latest"]
+	assert.False(t, found)
+	_, found = ImageAndHashKeyMapping[imageID]
+	assert.False(t, found)
+
+	// the container removed by the name is forgotten by the ID
+	IDAndNameMapping["c1d2e3f4a5b6"] = "roman-tmp"
+	IDAndHashKeyMapping["c1d2e3f4a5b6"] = roman
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/containers/roman-tmp?force=1",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 204,
+	})
+	_, found = IDAndHashKeyMapping["c1d2e3f4a5b6"]
+	assert.False(t, found)
+	_, found = IDAndNameMapping["c1d2e3f4a5b6"]
+	assert.False(t, found)
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/networks/create",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		RequestBody:        []byte(`{"Name":"roman-net","Driver":"bridge"}`),
+		ResponseBody:       []byte(`{"Id":"b5a4f3e2d1c0b9a8f7e6d5c4b3a291807f6e5d4c3b2a190803c1e2b9f4a8d7c6","Warning":""}`),
+		ResponseStatusCode: 201,
+	})
+	assert.Equal(t, roman, NetworkAndHashKeyMapping["roman-net"])
+	assert.Equal(t, roman, NetworkAndHashKeyMapping["b5a4f3e2d1c0b9a8f7e6d5c4b3a291807f6e5d4c3b2a190803c1e2b9f4a8d7c6"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/networks/roman-net",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 204,
+	})
+	_, found = NetworkAndHashKeyMapping["roman-net"]
+	assert.False(t, found)
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/secrets/create",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		RequestBody:        []byte(`{"Name":"roman-token","Data":"c2VjcmV0"}`),
+		ResponseBody:       []byte(`{"ID":"9z4wkaxo1dq8x0uwkm3e8s7ve"}`),
+		ResponseStatusCode: 201,
+	})
+	assert.Equal(t, roman, SecretAndHashKeyMapping["roman-token"])
+	assert.Equal(t, roman, SecretAndHashKeyMapping["9z4wkaxo1dq8x0uwkm3e8s7ve"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/services/create",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		RequestBody:        []byte(`{"Name":"roman-api","TaskTemplate":{"ContainerSpec":{"Image":"alpine"}}}`),
+		ResponseBody:       []byte(`{"ID":"3mfc1qk2n8w5v7x9z0b4d6h8j"}`),
+		ResponseStatusCode: 201,
+	})
+	assert.Equal(t, roman, ServiceAndHashKeyMapping["roman-api"])
+	assert.Equal(t, roman, ServiceAndHashKeyMapping["3mfc1qk2n8w5v7x9z0b4d6h8j"])
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/services/roman-api",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	_, found = ServiceAndHashKeyMapping["roman-api"]
+	assert.False(t, found)
+}
+
+// fakeDaemon answers the docker client of the plugin like docker daemon does:
+// every request goes through the plugin first, the unknown objects are 404
+type fakeDaemon struct {
+	containers map[string]types.ContainerJSON
+	images     map[string]types.ImageInspect
+	plugins    []types.Plugin
+	networks   map[string]types.NetworkResource
+	// the status of the answer by the path without the version, docker daemon can fail
+	failures map[string]int
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func (daemon fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	authPlugin := &CasbinAuthZPlugin{}
+	w.Header().Set("Api-Version", "1.41")
+	resp := authPlugin.AuthZReq(authorization.Request{
+		RequestMethod:  r.Method,
+		RequestURI:     r.URL.RequestURI(),
+		RequestHeaders: map[string]string{headerWithToken: r.Header.Get(headerWithToken)},
+	})
+	if !resp.Allow {
+		writeJSON(w, http.StatusForbidden, map[string]string{"message": resp.Msg})
+		return
+	}
+
+	api := regexp.MustCompile(`^/v\d+\.\d+/`).ReplaceAllString(r.URL.Path, "/")
+	if status, found := daemon.failures[api]; found {
+		writeJSON(w, status, map[string]string{"message": "Failure of " + api})
+		return
+	}
+	switch {
+	case api == "/_ping":
+		_, _ = w.Write([]byte("OK"))
+		return
+	case api == "/containers/json":
+		var list []types.Container
+		for _, inspect := range daemon.containers {
+			list = append(list, types.Container{ID: inspect.ID, Names: []string{inspect.Name}})
+		}
+		writeJSON(w, http.StatusOK, list)
+		return
+	case strings.HasPrefix(api, "/containers/") && strings.HasSuffix(api, "/json"):
+		nameOrID := strings.TrimSuffix(strings.TrimPrefix(api, "/containers/"), "/json")
+		for _, inspect := range daemon.containers {
+			if strings.HasPrefix(inspect.ID, nameOrID) || inspect.Name == "/"+nameOrID {
+				writeJSON(w, http.StatusOK, inspect)
+				return
+			}
+		}
+	case strings.HasPrefix(api, "/images/") && strings.HasSuffix(api, "/json"):
+		nameOrID := strings.TrimSuffix(strings.TrimPrefix(api, "/images/"), "/json")
+		for name, inspect := range daemon.images {
+			if name == nameOrID || strings.HasPrefix(inspect.ID, "sha256:"+strings.TrimPrefix(nameOrID, "sha256:")) {
+				writeJSON(w, http.StatusOK, inspect)
+				return
+			}
+		}
+	case strings.HasPrefix(api, "/plugins/") && strings.HasSuffix(api, "/json"):
+		nameOrID := strings.TrimSuffix(strings.TrimPrefix(api, "/plugins/"), "/json")
+		for _, inspect := range daemon.plugins {
+			if strings.HasPrefix(inspect.ID, nameOrID) || inspect.Name == nameOrID {
+				writeJSON(w, http.StatusOK, inspect)
+				return
+			}
+		}
+	case strings.HasPrefix(api, "/networks/"):
+		if inspect, found := daemon.networks[strings.TrimPrefix(api, "/networks/")]; found {
+			writeJSON(w, http.StatusOK, inspect)
+			return
+		}
+	}
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "No such object: " + api})
+}
+
+// startFakeDaemon points the docker client of the plugin to the daemon, call the result to stop it
+func startFakeDaemon(daemon fakeDaemon) func() {
+	server := httptest.NewServer(daemon)
+	previous, wasSet := os.LookupEnv("DOCKER_HOST")
+	_ = os.Setenv("DOCKER_HOST", "tcp://"+server.Listener.Addr().String())
+	return func() {
+		server.Close()
+		if wasSet {
+			_ = os.Setenv("DOCKER_HOST", previous)
+		} else {
+			_ = os.Unsetenv("DOCKER_HOST")
+		}
+	}
+}
+
+func TestRulesOfTheContainerImage(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	containerpolicy.PathToThePolicy = "testdata/container_policy.csv"
+	containerpolicy.PathToTheExecPolicy = "testdata/exec_policy.csv"
+	containerpolicy.PathToTheArchivePolicy = "testdata/archive_policy.csv"
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+	containerpolicy.ImageNamesOfID = ImageNames
+	defer func() { containerpolicy.ImageNamesOfID = nil }()
+	debianContainerID := "5e1d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d"
+	hubContainerID := "6f2e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e"
+	byIDContainerID := "7a3f1e0d9c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f"
+	debianImageID := "sha256:9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c"
+	for _, containerID := range []string{debianContainerID, hubContainerID, byIDContainerID} {
+		IDAndHashKeyMapping[containerID[:12]] = CalculateHash("0880d90d56bdcb9ad90aec20707b30e1")
+	}
+
+	stop := startFakeDaemon(fakeDaemon{
+		containers: map[string]types.ContainerJSON{
+			debianContainerID: {
+				ContainerJSONBase: &types.ContainerJSONBase{ID: debianContainerID, Name: "/user1-debian", HostConfig: &container.HostConfig{}},
+				Config:            &container.Config{Image: "debian:12"},
+			},
+			hubContainerID: {
+				ContainerJSONBase: &types.ContainerJSONBase{ID: hubContainerID, Name: "/user1-debian-hub", HostConfig: &container.HostConfig{}},
+				Config:            &container.Config{Image: "docker.io/library/debian:12"},
+			},
+			byIDContainerID: {
+				ContainerJSONBase: &types.ContainerJSONBase{ID: byIDContainerID, Name: "/user1-debian-id", HostConfig: &container.HostConfig{}},
+				Config:            &container.Config{Image: debianImageID},
+			},
+		},
+		images: map[string]types.ImageInspect{
+			"debian:12": {ID: debianImageID, RepoTags: []string{"debian:12"}},
+		},
+	})
+	defer stop()
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Exec of the command the image allows",
+			body: map[string]interface{}{"Cmd": []string{"ls", "/app"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{Allow: true},
+		},
+		{
+			name: "Exec of the command the image denies",
+			body: map[string]interface{}{"Cmd": []string{"apt-get", "install", "-y", "nmap"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Exec Cmd does not comply with the container policy: command"},
+		},
+		{
+			name: "Copy of the path the image allows",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian/archive?path=%2Fapp",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1"},
+			},
+			result: authorization.Response{Allow: true},
+		},
+		{
+			name: "Copy of the path the image denies",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian/archive?path=%2Fetc%2Fapt%2Fsources.list",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Archive path does not comply with the archive policy: read"},
+		},
+		{
+			name: "Exec of the command the image denies by the whole name of the image",
+			body: map[string]interface{}{"Cmd": []string{"apt-get", "install", "-y", "nmap"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian-hub/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Exec Cmd does not comply with the container policy: command"},
+		},
+		{
+			name: "Copy of the path the image denies by the whole name of the image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian-hub/archive?path=%2Fetc%2Fapt",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Archive path does not comply with the archive policy: read"},
+		},
+		{
+			name: "Exec of the command the image denies by the ID of the image",
+			body: map[string]interface{}{"Cmd": []string{"apt-get", "install", "-y", "nmap"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian-id/exec",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Exec Cmd does not comply with the container policy: command"},
+		},
+		{
+			name: "Copy of the path the image denies by the ID of the image",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian-id/archive?path=%2Fetc%2Fapt",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Archive path does not comply with the archive policy: read"},
+		},
+		{
+			name: "Copy of the path the image denies hidden inside of the escaped query",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/containers/user1-debian/archive?x=%26path%3D%2Fapp&path=%2Fetc%2Fapt%2Fsources.list",
+				RequestMethod:  "GET",
+				RequestHeaders: map[string]string{"AuthHeader": "0880d90d56bdcb9ad90aec20707b30e1"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Archive path does not comply with the archive policy: read"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := json.Marshal(testCase.body)
+			if err != nil {
+				log.Println("Can't Marshal data", err)
+			}
+			testCase.request.RequestBody = data
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+}
+
+func TestAuthzPluginByID(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	DefineAdminToken("f3ab0df5dfdb79a088d1cb3c7c6c3658a222ac0d1b6d59ee031b62460c92e9b8")
+	defer DefineAdminToken("")
+
+	stop := startFakeDaemon(fakeDaemon{plugins: []types.Plugin{
+		{ID: "4a2f1b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a", Name: "container-authz-plugin:latest"},
+		{ID: "7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f0e9d8c7b6a5f4e3d2c1b0a9f8e7d6c", Name: "vieux/sshfs:latest"},
+	}})
+	defer stop()
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test admin disables the authorization plugin by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/4a2f1b9c8d7e/disable?force=1",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Nobody can switch off the authorization plugin: 4a2f1b9c8d7e"},
+		},
+		{
+			name: "Test admin disables the unknown plugin by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/0b1c2d3e4f5a/disable",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. Nobody can switch off the authorization plugin: 0b1c2d3e4f5a"},
+		},
+		{
+			name: "Test admin disables sshfs by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.42/plugins/7d6c5b4a3f2e/disable",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "9f1b2c3d4e5f60718293a4b5c6d7e8f9"},
+			},
+			result: authorization.Response{Allow: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+}
+
+func TestOwnerOfTheImageID(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	roman := "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	imageID := "sha256:9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b"
+
+	stop := startFakeDaemon(fakeDaemon{images: map[string]types.ImageInspect{
+		"registry.corp.internal/roman/api:4.0": {ID: imageID},
+	}})
+	defer stop()
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/create?fromImage=registry.corp.internal%2Froman%2Fapi&tag=4.0",
+		RequestMethod:      "POST",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	assert.Equal(t, roman, ImageAndHashKeyMapping[imageID])
+
+	keyHash, found := ImageOwner("9a8b7c6d")
+	assert.True(t, found)
+	assert.Equal(t, roman, keyHash)
+
+	authPlugin.AuthZRes(authorization.Request{
+		RequestURI:         "/v1.41/images/9a8b7c6d?force=1",
+		RequestMethod:      "DELETE",
+		RequestHeaders:     map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+		ResponseStatusCode: 200,
+	})
+	_, found = ImageAndHashKeyMapping[imageID]
+	assert.False(t, found)
+}
+
+func TestTagByTheImageID(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	containerpolicy.PathToTheImagePolicy = "testdata/image_policy.csv"
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+
+	stop := startFakeDaemon(fakeDaemon{images: map[string]types.ImageInspect{
+		"evil.example.com/miner:latest": {ID: "sha256:1f2e3d4c5b6a79880716253443526170819a0b1c2d3e4f5a6b7c8d9e0f1a2b3c", RepoTags: []string{"evil.example.com/miner:latest"}},
+		"alpine:3.19":                   {ID: "sha256:2a3b4c5d6e7f80910a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f6071", RepoTags: []string{"alpine:3.19"}},
+	}})
+	defer stop()
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test tag of the image from unknown registry by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/1f2e3d4c5b6a/tag?repo=roman%2Fminer&tag=1.0",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Image evil.example.com/miner:latest does not comply with the image policy: registry"},
+		},
+		{
+			name: "Test tag of the image from docker hub by the ID",
+			request: authorization.Request{
+				RequestURI:     "/v1.41/images/sha256:2a3b4c5d6e7f/tag?repo=roman%2Falpine&tag=3.19",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164"},
+			},
+			result: authorization.Response{Allow: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
+		})
+	}
+}
+
+func TestNetworkConfigFrom(t *testing.T) {
+	authPlugin := &CasbinAuthZPlugin{}
+	containerpolicy.PathToTheNetworkPolicy = "testdata/network_policy.csv"
+	identity.PathToTheUsers = "../identity/testdata/users.csv"
+	NetworkAndHashKeyMapping["anna-cfg"] = "0c5de4e4a0d7a5be5a3b6c1b2e1b2a4a3d2f5d6e7c8b9a0f1e2d3c4b5a697887"
+	NetworkAndHashKeyMapping["roman-cfg"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+	NetworkAndHashKeyMapping["roman-lab-cfg"] = "7c3aa42f54e7848da56244fbf7844ef3687b6d172cf5a289ad0092da46aeb713"
+
+	stop := startFakeDaemon(fakeDaemon{networks: map[string]types.NetworkResource{
+		"anna-cfg":      {Name: "anna-cfg", Driver: "null", ConfigOnly: true, Options: map[string]string{"parent": "eth1"}},
+		"roman-cfg":     {Name: "roman-cfg", Driver: "null", ConfigOnly: true, Options: map[string]string{"parent": "eth0"}},
+		"roman-lab-cfg": {Name: "roman-lab-cfg", Driver: "null", ConfigOnly: true, Options: map[string]string{"parent": "eth1"}},
+		"ghost-cfg":     {Name: "ghost-cfg", Driver: "null", ConfigOnly: true, Options: map[string]string{"parent": "eth1"}},
+	}})
+	defer stop()
+
+	testCases := []AdmitTestCase{
+		{
+			name: "Test macvlan from other's config",
+			body: map[string]interface{}{"Name": "roman-mac", "Driver": "macvlan", "ConfigFrom": map[string]string{"Network": "anna-cfg"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: anna-cfg"},
+		},
+		{
+			name: "Test macvlan from the config without the owner",
+			body: map[string]interface{}{"Name": "roman-mac", "Driver": "macvlan", "ConfigFrom": map[string]string{"Network": "ghost-cfg"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin. That's not your network: ghost-cfg"},
+		},
+		{
+			name: "Test macvlan on the main interface from own config",
+			body: map[string]interface{}{"Name": "roman-mac", "Driver": "macvlan", "ConfigFrom": map[string]string{"Network": "roman-cfg"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{
+				Allow: false, Msg: "Access denied by AuthPlugin.Network Body does not comply with the network policy: options.parent"},
+		},
+		{
+			name: "Test macvlan on the lab interface from own config",
+			body: map[string]interface{}{"Name": "roman-mac", "Driver": "macvlan", "ConfigFrom": map[string]string{"Network": "roman-lab-cfg"}},
+			request: authorization.Request{
+				RequestURI:     "/v1.42/networks/create",
+				RequestMethod:  "POST",
+				RequestHeaders: map[string]string{"AuthHeader": "75ea549427986bbea5d2292f7c00f164", "Content-Type": "application/json"},
+			},
+			result: authorization.Response{Allow: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := json.Marshal(testCase.body)
+			if err != nil {
+				log.Println("Can't Marshal data", err)
+			}
+			testCase.request.RequestBody = data
+			assert.Equal(t, testCase.result, authPlugin.AuthZReq(testCase.request))
 		})
 	}
 }